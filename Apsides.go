@@ -0,0 +1,130 @@
+package sampa
+
+import (
+	"context"
+	"sort"
+	"time"
+)
+
+// ApsisKind defines the closest or farthest point of the lunar orbit
+type ApsisKind uint32
+
+// enumeration for the apsides of the lunar orbit
+const (
+	Perigee ApsisKind = 0 //minimum of the distance between earth and moon
+	Apogee  ApsisKind = 1 //maximum of the distance between earth and moon
+)
+
+// MoonPhaseKind defines a principal phase of the moon
+type MoonPhaseKind uint32
+
+// enumeration for the principal phases of the moon
+const (
+	NewMoon  MoonPhaseKind = 0 //apparent geocentric longitudes of moon and sun are equal
+	FullMoon MoonPhaseKind = 1 //apparent geocentric longitudes of moon and sun differ by 180 degrees
+)
+
+// default distance thresholds of super and micro moons [kilometers]
+const (
+	SuperMoonDistance = 360000.0
+	MicroMoonDistance = 405000.0
+)
+
+// Apsis is a perigee or apogee of the moon
+type Apsis struct {
+	Time     time.Time
+	Kind     ApsisKind
+	Distance float64 //distance between the centers of earth and moon [kilometers]
+}
+
+// MoonPhase is a new or full moon
+type MoonPhase struct {
+	Time      time.Time
+	Kind      MoonPhaseKind
+	Distance  float64 //distance between the centers of earth and moon [kilometers]
+	SuperMoon bool    //distance below the super moon threshold
+	MicroMoon bool    //distance above the micro moon threshold
+}
+
+// MoonPhaseOptions defines the distance thresholds of super and micro moons
+type MoonPhaseOptions struct {
+	SuperMoonDistance float64 //super moon if closer [kilometers], SuperMoonDistance if zero
+	MicroMoonDistance float64 //micro moon if farther [kilometers], MicroMoonDistance if zero
+}
+
+func (s *sampa) moonDistanceAt(t time.Time) (float64, error) {
+	g, err := s.geocentricAt(t)
+	return g.moonDistance, err
+}
+
+///////////////////////////////////////////////////////////////////////////////////////////
+// Find the perigees and apogees of the moon from start to end: the geocentric distance is
+// sampled every six hours and each local extremum is refined to one second. The date of the
+// spa data and the SAMPA outputs are restored afterwards. Progress counts the samples.
+///////////////////////////////////////////////////////////////////////////////////////////
+func (s *sampa) CalculateApsides(ctx context.Context, start time.Time, end time.Time, progress Progress) (apsides []Apsis, err error) {
+	defer restoreDate(s, &err)()
+	start = start.Truncate(time.Second)
+	first, last := start.Add(-searchStep), end.Add(searchStep)
+	pc := newProgressCounter(progress, stepCount(first, last, searchStep))
+	extrema, err := scanExtrema(ctx, first, last, searchStep, pc, s.moonDistanceAt)
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range extrema {
+		a := Apsis{Time: e.t, Kind: Perigee, Distance: e.v}
+		if e.maximum {
+			a.Kind = Apogee
+		}
+		if !a.Time.Before(start) && !a.Time.After(end) {
+			apsides = append(apsides, a)
+		}
+	}
+	return apsides, nil
+}
+
+///////////////////////////////////////////////////////////////////////////////////////////
+// Find the new and full moons from start to end with the distance between earth and moon,
+// flagged as super or micro moons by the distance thresholds of opts. The elongation is
+// sampled every six hours and each crossing is refined to one second. The date of the spa
+// data and the SAMPA outputs are restored afterwards. Progress counts the samples of both
+// phases.
+///////////////////////////////////////////////////////////////////////////////////////////
+func (s *sampa) CalculateMoonPhases(ctx context.Context, start time.Time, end time.Time, opts MoonPhaseOptions, progress Progress) (phases []MoonPhase, err error) {
+	if opts.SuperMoonDistance == 0 {
+		opts.SuperMoonDistance = SuperMoonDistance
+	}
+	if opts.MicroMoonDistance == 0 {
+		opts.MicroMoonDistance = MicroMoonDistance
+	}
+	defer restoreDate(s, &err)()
+	start = start.Truncate(time.Second)
+
+	pc := newProgressCounter(progress, 2*stepCount(start, end.Add(searchStep), searchStep))
+	for kind := NewMoon; kind <= FullMoon; kind++ {
+		//elongation of the moon from the sun relative to the phase, positive after the phase
+		offset := 180 * float64(kind)
+		roots, err := scanRoots(ctx, start, end.Add(searchStep), searchStep, pc, 90, func(t time.Time) (float64, error) {
+			g, err := s.geocentricAt(t)
+			return wrapDegrees(g.moonLamda - g.sunLamda - offset), err
+		})
+		if err != nil {
+			return nil, err
+		}
+		for _, r := range roots {
+			if !r.rising || r.t.After(end) {
+				continue
+			}
+			p := MoonPhase{Time: r.t, Kind: kind}
+			p.Distance, err = s.moonDistanceAt(p.Time)
+			if err != nil {
+				return nil, err
+			}
+			p.SuperMoon = p.Distance < opts.SuperMoonDistance
+			p.MicroMoon = p.Distance > opts.MicroMoonDistance
+			phases = append(phases, p)
+		}
+	}
+	sort.Slice(phases, func(i, j int) bool { return phases[i].Time.Before(phases[j].Time) })
+	return phases, nil
+}
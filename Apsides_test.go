@@ -0,0 +1,78 @@
+package sampa
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+)
+
+//sampa for searches in October 2024
+func october2024() (Sampa, error) {
+	in := referenceInput()
+	in.setDate(time.Date(2024, 10, 1, 0, 0, 0, 0, time.UTC))
+	in.DeltaT = 69.2
+	return in.sampa()
+}
+
+//progress that fails the test if the total changes or the count decreases, returns the last report
+func monotonicProgress(t *testing.T) (Progress, *[2]int) {
+	var last [2]int
+	return func(done int, total int) {
+		if done < last[0] || (last[1] != 0 && total != last[1]) || done > total {
+			t.Errorf("progress %d of %d after %d of %d", done, total, last[0], last[1])
+		}
+		last = [2]int{done, total}
+	}, &last
+}
+
+//published apsides and phases of October 2024 (USNO, timeanddate.com): the perigee of
+//2024-10-17 00:53 UTC at 357172 km, the new moon of 2024-10-02 18:49 UTC and the super
+//full moon of 2024-10-17 11:26 UTC
+func TestMoonPhases(t *testing.T) {
+	s, err := october2024()
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Date(2024, 10, 1, 0, 0, 0, 0, time.UTC)
+	end := time.Date(2024, 10, 31, 0, 0, 0, 0, time.UTC)
+
+	apsides, err := s.CalculateApsides(context.Background(), start, end, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var perigee *Apsis
+	for i := range apsides {
+		if apsides[i].Kind == Perigee {
+			perigee = &apsides[i]
+		}
+	}
+	if len(apsides) != 3 || perigee == nil {
+		t.Fatalf("apsides %v, want apogee, perigee, apogee", apsides)
+	}
+	if want := time.Date(2024, 10, 17, 0, 53, 0, 0, time.UTC); math.Abs(perigee.Time.Sub(want).Minutes()) > 2 || math.Abs(perigee.Distance-357172) > 5 {
+		t.Errorf("perigee %v at %.0f km, want %v at 357172 km", perigee.Time, perigee.Distance, want)
+	}
+
+	progress, last := monotonicProgress(t)
+	phases, err := s.CalculateMoonPhases(context.Background(), start, end, MoonPhaseOptions{}, progress)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(phases) != 2 || phases[0].Kind != NewMoon || phases[1].Kind != FullMoon {
+		t.Fatalf("phases %v, want new and full moon", phases)
+	}
+	if want := time.Date(2024, 10, 2, 18, 49, 0, 0, time.UTC); math.Abs(phases[0].Time.Sub(want).Minutes()) > 2 || !phases[0].MicroMoon {
+		t.Errorf("new moon %v micro %v, want %v and a micro moon", phases[0].Time, phases[0].MicroMoon, want)
+	}
+	if want := time.Date(2024, 10, 17, 11, 26, 0, 0, time.UTC); math.Abs(phases[1].Time.Sub(want).Minutes()) > 2 || !phases[1].SuperMoon {
+		t.Errorf("full moon %v super %v, want %v and a super moon", phases[1].Time, phases[1].SuperMoon, want)
+	}
+	//one count spans both phases
+	if last[0] != last[1] || last[1] != 2*stepCount(start, end.Add(searchStep), searchStep) {
+		t.Errorf("progress ended at %d of %d", last[0], last[1])
+	}
+	if !s.GetSpaData().GetDate().Equal(start) {
+		t.Errorf("spa date %v not restored", s.GetSpaData().GetDate())
+	}
+}
@@ -0,0 +1,236 @@
+package sampa
+
+import (
+	"context"
+	"errors"
+	"math"
+	"sort"
+
+	"github.com/maltegrosse/go-bird"
+)
+
+const (
+	fitMaxIterations = 2000 //iteration limit of the simplex search
+	fitInitialStep   = 0.1  //initial simplex size relative to the parameter ranges
+)
+
+// ParameterRange defines the lower and upper bound of a fitted parameter, equal bounds fix the parameter
+type ParameterRange struct {
+	Min float64
+	Max float64
+}
+
+// BirdBounds defines the ranges of the fitted Bird model inputs
+type BirdBounds struct {
+	Ozone  ParameterRange //total column ozone thickness [cm]
+	Water  ParameterRange //total column water vapor [cm]
+	Taua   ParameterRange //broadband aerosol optical depth
+	Ba     ParameterRange //forward scattering ratio
+	Albedo ParameterRange //ground reflectance
+}
+
+// DefaultBirdBounds covers typical clear-sky atmospheres
+var DefaultBirdBounds = BirdBounds{
+	Ozone:  ParameterRange{Min: 0.05, Max: 0.6},
+	Water:  ParameterRange{Min: 0, Max: 6},
+	Taua:   ParameterRange{Min: 0, Max: 1.5},
+	Ba:     ParameterRange{Min: 0.5, Max: 0.95},
+	Albedo: ParameterRange{Min: 0, Max: 0.9},
+}
+
+// BirdFit is the result of fitting the Bird model inputs to measurements
+type BirdFit struct {
+	Bird       bird.Bird //Bird model with the fitted inputs for the current instant of the Sampa
+	Rmse       float64   //root mean square residual of all fitted samples [W/m^2]
+	Samples    int       //number of fitted samples
+	Iterations int       //iterations of the simplex search
+}
+
+func (b *BirdBounds) ranges() [5]ParameterRange {
+	return [5]ParameterRange{b.Ozone, b.Water, b.Taua, b.Ba, b.Albedo}
+}
+
+func (b *BirdBounds) validate() error {
+	names := [5]string{"ozone", "water", "taua", "ba", "albedo"}
+	for i, r := range b.ranges() {
+		if err := checkRange(names[i]+".min", r.Min, 0, 100, ErrInvalidBird); err != nil {
+			return err
+		}
+		if err := checkRange(names[i]+".max", r.Max, r.Min, 100, ErrInvalidBird); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+//nelder mead search of the minimum of f in the unit hypercube, points outside are projected onto it
+func nelderMead(ctx context.Context, f func([]float64) float64, x0 []float64, tolerance float64, maxIterations int) ([]float64, int, error) {
+	n := len(x0)
+	project := func(x []float64) []float64 {
+		for i := range x {
+			x[i] = math.Max(0, math.Min(1, x[i]))
+		}
+		return x
+	}
+	type vertex struct {
+		x []float64
+		f float64
+	}
+	simplex := make([]vertex, n+1)
+	simplex[0] = vertex{x: project(append([]float64(nil), x0...))}
+	for i := 0; i < n; i++ {
+		x := append([]float64(nil), simplex[0].x...)
+		if x[i]+fitInitialStep <= 1 {
+			x[i] += fitInitialStep
+		} else {
+			x[i] -= fitInitialStep
+		}
+		simplex[i+1] = vertex{x: x}
+	}
+	for i := range simplex {
+		simplex[i].f = f(simplex[i].x)
+	}
+	//point on the line from the centroid c through the worst vertex w, x = c + t (c - w)
+	along := func(c []float64, w []float64, t float64) vertex {
+		x := make([]float64, n)
+		for i := range x {
+			x[i] = c[i] + t*(c[i]-w[i])
+		}
+		project(x)
+		return vertex{x: x, f: f(x)}
+	}
+
+	iteration := 0
+	for ; iteration < maxIterations; iteration++ {
+		if err := ctx.Err(); err != nil {
+			return nil, iteration, err
+		}
+		sort.Slice(simplex, func(i, j int) bool { return simplex[i].f < simplex[j].f })
+		size := 0.
+		for _, v := range simplex[1:] {
+			for i := range v.x {
+				size = math.Max(size, math.Abs(v.x[i]-simplex[0].x[i]))
+			}
+		}
+		if size < tolerance {
+			break
+		}
+
+		c := make([]float64, n)
+		for _, v := range simplex[:n] {
+			for i := range c {
+				c[i] += v.x[i] / float64(n)
+			}
+		}
+		worst := simplex[n]
+		reflected := along(c, worst.x, 1)
+		switch {
+		case reflected.f < simplex[0].f:
+			expanded := along(c, worst.x, 2)
+			if expanded.f < reflected.f {
+				simplex[n] = expanded
+			} else {
+				simplex[n] = reflected
+			}
+			continue
+		case reflected.f < simplex[n-1].f:
+			simplex[n] = reflected
+			continue
+		case reflected.f < worst.f:
+			if contracted := along(c, worst.x, 0.5); contracted.f <= reflected.f {
+				simplex[n] = contracted
+				continue
+			}
+		default:
+			if contracted := along(c, worst.x, -0.5); contracted.f < worst.f {
+				simplex[n] = contracted
+				continue
+			}
+		}
+		//shrink towards the best vertex
+		for j := 1; j <= n; j++ {
+			for i := range simplex[j].x {
+				simplex[j].x[i] = simplex[0].x[i] + 0.5*(simplex[j].x[i]-simplex[0].x[i])
+			}
+			simplex[j].f = f(simplex[j].x)
+		}
+	}
+	sort.Slice(simplex, func(i, j int) bool { return simplex[i].f < simplex[j].f })
+	return simplex[0].x, iteration, nil
+}
+
+///////////////////////////////////////////////////////////////////////////////////////////
+// Fit the Bird model inputs (ozone, water, taua, ba, albedo) within bounds to measured
+// clear-sky irradiances, using the SAMPA sun geometry and unshaded disk fraction of each
+// sample. The search starts at the Bird inputs of s, samples with the sun close to the
+// horizon are ignored. The date of the spa data is restored afterwards. Progress counts the
+// measurements, the search itself stops when ctx is canceled.
+///////////////////////////////////////////////////////////////////////////////////////////
+func FitBird(ctx context.Context, s Sampa, ms []Measurement, bounds BirdBounds, progress Progress) (BirdFit, error) {
+	var fit BirdFit
+	err := bounds.validate()
+	if err != nil {
+		return fit, err
+	}
+	_, all, err := residuals(ctx, s, ms, progress)
+	if err != nil {
+		return fit, err
+	}
+	var samples []fitSample
+	count := 0
+	for _, fs := range all {
+		if fs.zenith >= fitMaxZenith || (math.IsNaN(fs.ghi) && math.IsNaN(fs.dni)) {
+			continue
+		}
+		samples = append(samples, fs)
+		if !math.IsNaN(fs.ghi) {
+			count++
+		}
+		if !math.IsNaN(fs.dni) {
+			count++
+		}
+	}
+	if len(samples) == 0 {
+		return fit, errors.New("no clear-sky samples to fit")
+	}
+
+	//free parameters are searched in the unit interval of their range
+	b := s.GetBirdData()
+	start := [5]float64{b.GetOzone(), b.GetWater(), b.GetTaua(), b.GetBa(), b.GetAlbedo()}
+	ranges := bounds.ranges()
+	var free []int
+	var x0 []float64
+	for i, r := range ranges {
+		if r.Max > r.Min {
+			free = append(free, i)
+			x0 = append(x0, (start[i]-r.Min)/(r.Max-r.Min))
+		}
+	}
+	params := func(x []float64) [5]float64 {
+		var p [5]float64
+		for i, r := range ranges {
+			p[i] = r.Min
+		}
+		for j, i := range free {
+			p[i] = ranges[i].Min + x[j]*(ranges[i].Max-ranges[i].Min)
+		}
+		return p
+	}
+	cost := func(x []float64) float64 {
+		p := params(x)
+		return fitCost(samples, p[0], p[1], p[2], p[3], p[4])
+	}
+
+	x := x0
+	if len(free) > 0 {
+		x, fit.Iterations, err = nelderMead(ctx, cost, x0, fitTolerance, fitMaxIterations)
+		if err != nil {
+			return fit, err
+		}
+	}
+	p := params(x)
+	fit.Rmse = math.Sqrt(cost(x) / float64(count))
+	fit.Samples = len(samples)
+	fit.Bird, err = bird.NewBird(s.GetSunZenith(), s.GetSpaData().GetR(), s.GetSpaData().GetPressure(), p[0], p[1], p[2], p[3], p[4], s.GetASulPct()/100)
+	return fit, err
+}
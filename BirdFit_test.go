@@ -0,0 +1,83 @@
+package sampa
+
+import (
+	"context"
+	"math"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNelderMead(t *testing.T) {
+	want := []float64{0.3, 0.7, 1}
+	f := func(x []float64) float64 {
+		//the minimum of the last coordinate lies outside the unit cube
+		return (x[0]-want[0])*(x[0]-want[0]) + 4*(x[1]-want[1])*(x[1]-want[1]) + (x[2]-1.5)*(x[2]-1.5)
+	}
+	x, iterations, err := nelderMead(context.Background(), f, []float64{0.5, 0.5, 0.5}, 1e-8, fitMaxIterations)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range want {
+		if math.Abs(x[i]-want[i]) > 1e-6 {
+			t.Errorf("x[%d] = %.8f, want %g (%d iterations)", i, x[i], want[i], iterations)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, _, err := nelderMead(ctx, f, []float64{0.5, 0.5, 0.5}, 1e-8, fitMaxIterations); err != context.Canceled {
+		t.Errorf("canceled search returned %v", err)
+	}
+}
+
+//the Bird inputs of synthetic measurements are recovered, fixed parameters stay at their bound
+func TestFitBird(t *testing.T) {
+	start := time.Date(2017, 8, 21, 14, 0, 0, 0, time.UTC)
+	end := time.Date(2017, 8, 21, 19, 0, 0, 0, time.UTC)
+	ms, err := ReadMeasurements(strings.NewReader(syntheticMeasurements(t, 0.2, start, end)), MeasurementOptions{Comma: ';'})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := illinoisEclipseInput().sampa()
+	if err != nil {
+		t.Fatal(err)
+	}
+	in := illinoisEclipseInput()
+	bounds := BirdBounds{
+		Ozone:  ParameterRange{Min: in.Ozone, Max: in.Ozone},
+		Water:  ParameterRange{Min: in.Water, Max: in.Water},
+		Taua:   DefaultBirdBounds.Taua,
+		Ba:     ParameterRange{Min: in.Ba, Max: in.Ba},
+		Albedo: DefaultBirdBounds.Albedo,
+	}
+	fit, err := FitBird(context.Background(), s, ms, bounds, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := fit.Bird
+	if math.Abs(b.GetTaua()-0.2) > 1e-3 || math.Abs(b.GetAlbedo()-in.Albedo) > 1e-2 {
+		t.Errorf("fitted taua %.5f, albedo %.5f, want 0.2, %g", b.GetTaua(), b.GetAlbedo(), in.Albedo)
+	}
+	if b.GetOzone() != in.Ozone || b.GetWater() != in.Water || b.GetBa() != in.Ba {
+		t.Errorf("fixed parameters changed: ozone %g, water %g, ba %g", b.GetOzone(), b.GetWater(), b.GetBa())
+	}
+	if fit.Rmse > 0.1 || fit.Samples == 0 || fit.Samples > len(ms) {
+		t.Errorf("RMSE %.4f W/m^2 of %d samples", fit.Rmse, fit.Samples)
+	}
+
+	//a true taua above the range: the fit stays at the upper bound
+	bounds.Taua = ParameterRange{Min: 0, Max: 0.1}
+	fit, err = FitBird(context.Background(), s, ms, bounds, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if taua := fit.Bird.GetTaua(); taua > 0.1 || taua < 0.099 {
+		t.Errorf("fitted taua %.5f, want the bound 0.1", taua)
+	}
+
+	bounds.Taua = ParameterRange{Min: 0.5, Max: 0.1}
+	if _, err := FitBird(context.Background(), s, ms, bounds, nil); err == nil {
+		t.Error("inverted bounds accepted")
+	}
+}
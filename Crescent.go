@@ -0,0 +1,171 @@
+package sampa
+
+import (
+	"context"
+	"errors"
+	"math"
+	"time"
+)
+
+// YallopCategory defines the visibility of the young crescent moon by the Yallop q value
+type YallopCategory uint32
+
+// enumeration for the Yallop visibility categories
+const (
+	YallopA YallopCategory = 0 //q > +0.216, easily visible
+	YallopB YallopCategory = 1 //q > -0.014, visible under perfect conditions
+	YallopC YallopCategory = 2 //q > -0.160, may need optical aid to find the crescent
+	YallopD YallopCategory = 3 //q > -0.232, will need optical aid to find the crescent
+	YallopE YallopCategory = 4 //q > -0.293, not visible with a telescope
+	YallopF YallopCategory = 5 //below the Danjon limit, not visible
+)
+
+// OdehZone defines the visibility of the young crescent moon by the Odeh V value
+type OdehZone uint32
+
+// enumeration for the Odeh visibility zones
+const (
+	OdehA OdehZone = 0 //V >= 5.65, visible by naked eye
+	OdehB OdehZone = 1 //V >= 2.00, visible by optical aid, could be seen by naked eye
+	OdehC OdehZone = 2 //V >= -0.96, visible by optical aid only
+	OdehD OdehZone = 3 //not visible even by optical aid
+)
+
+// CrescentVisibility is the prediction of the first visibility of the crescent moon on one evening
+type CrescentVisibility struct {
+	Sunset   time.Time //upper sun limb at the horizon
+	Moonset  time.Time //upper moon limb at the horizon, closest to the sunset
+	Lag      float64   //moonset minus sunset [minutes]
+	BestTime time.Time //sunset plus 4/9 of the lag, the sunset if the moon sets first
+	Arcl     float64   //arc of light, topocentric elongation of the moon from the sun [degrees]
+	Arcv     float64   //arc of vision, airless topocentric elevation of the moon above the sun [degrees]
+	ArcvGeo  float64   //arc of vision, airless geocentric elevation of the moon above the sun [degrees]
+	Daz      float64   //azimuth of the sun minus azimuth of the moon [degrees]
+	Width    float64   //topocentric crescent width [arc minutes]
+	YallopQ  float64   //Yallop q value, from the geocentric arc of vision
+	Yallop   YallopCategory
+	OdehV    float64 //Odeh V value, from the topocentric arc of vision
+	Odeh     OdehZone
+}
+
+//arc of vision at which a crescent of width w [arc minutes] becomes visible in the Yallop criterion [degrees]
+func yallopArcv(w float64) float64 {
+	return 11.8371 - 6.3226*w + 0.7319*w*w - 0.1018*w*w*w
+}
+
+func yallopCategory(q float64) YallopCategory {
+	switch {
+	case q > 0.216:
+		return YallopA
+	case q > -0.014:
+		return YallopB
+	case q > -0.160:
+		return YallopC
+	case q > -0.232:
+		return YallopD
+	case q > -0.293:
+		return YallopE
+	}
+	return YallopF
+}
+
+func odehZone(v float64) OdehZone {
+	switch {
+	case v >= 5.65:
+		return OdehA
+	case v >= 2:
+		return OdehB
+	case v >= -0.96:
+		return OdehC
+	}
+	return OdehD
+}
+
+//elevation of the upper sun limb above the horizon [degrees]
+func (s *sampa) sunLimbAt(t time.Time) (float64, error) {
+	s.spaData.SetDate(t)
+	err := s.Calculate()
+	return s.sunE + s.rs, err
+}
+
+//elevation of the upper moon limb above the horizon [degrees]
+func (s *sampa) moonLimbAt(t time.Time) (float64, error) {
+	s.spaData.SetDate(t)
+	err := s.Calculate()
+	return s.mpaData.e + s.rm, err
+}
+
+///////////////////////////////////////////////////////////////////////////////////////////
+// Predict the first visibility of the crescent moon on the evening of date (in its
+// location): the sunset after local noon and the moonset closest to it give the best time
+// of sighting, sunset plus 4/9 of the lag (Yallop 1997). At the best time the arc of light
+// and the crescent width from the topocentric moon radius give the Yallop q value with the
+// geocentric airless arc of vision (Yallop 1997) and the Odeh V value with the topocentric
+// one (Odeh 2004). The elevations are sampled every ten minutes, sunset and moonset are
+// refined to one second. The date of the spa data and the SAMPA outputs are restored
+// afterwards. Progress counts the samples of sunset and moonset together.
+///////////////////////////////////////////////////////////////////////////////////////////
+func (s *sampa) CalculateCrescentVisibility(ctx context.Context, date time.Time, progress Progress) (c CrescentVisibility, err error) {
+	defer restoreDate(s, &err)()
+	function := s.function
+	defer func() {
+		s.function = function
+	}()
+	s.function = SampaNoIrr
+
+	noon := time.Date(date.Year(), date.Month(), date.Day(), 12, 0, 0, 0, date.Location())
+	//the moonset is searched within twelve hours of the sunset, one count spans both scans
+	moonsetWindow := 12 * time.Hour
+	moonsetSamples := stepCount(noon, noon.Add(2*moonsetWindow), nightStep)
+	pc := newProgressCounter(progress, stepCount(noon, noon.AddDate(0, 0, 1), nightStep)+moonsetSamples)
+	roots, err := scanRoots(ctx, noon, noon.AddDate(0, 0, 1), nightStep, pc, 90, s.sunLimbAt)
+	if err != nil {
+		return c, err
+	}
+	for _, r := range roots {
+		if !r.rising {
+			c.Sunset = r.t
+			break
+		}
+	}
+	if c.Sunset.IsZero() {
+		return c, errors.New("no sunset on " + noon.Format("2006-01-02"))
+	}
+
+	first, last := c.Sunset.Add(-moonsetWindow), c.Sunset.Add(moonsetWindow)
+	roots, err = scanRoots(ctx, first, last, nightStep, pc, 90, s.moonLimbAt)
+	if err != nil {
+		return c, err
+	}
+	for _, r := range roots {
+		if !r.rising && (c.Moonset.IsZero() || math.Abs(r.t.Sub(c.Sunset).Seconds()) < math.Abs(c.Moonset.Sub(c.Sunset).Seconds())) {
+			c.Moonset = r.t
+		}
+	}
+	if c.Moonset.IsZero() {
+		return c, errors.New("no moonset near the sunset on " + noon.Format("2006-01-02"))
+	}
+	c.Lag = c.Moonset.Sub(c.Sunset).Minutes()
+
+	c.BestTime = c.Sunset
+	if c.Lag > 0 {
+		c.BestTime = c.Sunset.Add(c.Moonset.Sub(c.Sunset) * 4 / 9).Truncate(time.Second)
+	}
+	s.spaData.SetDate(c.BestTime)
+	if err := s.Calculate(); err != nil {
+		return c, err
+	}
+	m := &s.mpaData
+	c.Arcl = s.angularSeparation(s.spaData.GetAlphaPrime(), s.spaData.GetDeltaPrime(), m.alphaPrime, m.deltaPrime)
+	c.Arcv = m.e0 - s.spaData.GetE0()
+	latitude := s.spaData.GetLatitude()
+	c.ArcvGeo = s.topocentricElevationAngle(latitude, m.delta, m.h) -
+		s.topocentricElevationAngle(latitude, s.spaData.GetDelta(), s.spaData.GetH())
+	c.Daz = wrapDegrees(s.spaData.GetAzimuth() - m.azimuth)
+	c.Width = 60 * s.rm * (1 - math.Cos(s.deg2rad(c.Arcl)))
+	c.YallopQ = (c.ArcvGeo - yallopArcv(c.Width)) / 10
+	c.Yallop = yallopCategory(c.YallopQ)
+	c.OdehV = c.Arcv - (yallopArcv(c.Width) - 4.6720)
+	c.Odeh = odehZone(c.OdehV)
+	return c, nil
+}
@@ -0,0 +1,81 @@
+package sampa
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+)
+
+//crescent of Ramadan 1444 at Mecca: the new moon of 2023-03-21 17:23 UTC follows the sunset of
+//that day, the crescent was reported seen by naked eye on the evening of 2023-03-22
+func TestCrescentVisibility(t *testing.T) {
+	in := referenceInput()
+	in.DeltaT = 69.2
+	in.Latitude, in.Longitude, in.Elevation = 21.42, 39.83, 300
+	s, err := in.sampa()
+	if err != nil {
+		t.Fatal(err)
+	}
+	mecca := time.FixedZone("AST", 3*3600)
+
+	progress, last := monotonicProgress(t)
+	c, err := s.CalculateCrescentVisibility(context.Background(), time.Date(2023, 3, 21, 0, 0, 0, 0, mecca), progress)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Lag >= 0 || c.Yallop != YallopF || c.Odeh != OdehD || !c.BestTime.Equal(c.Sunset) {
+		t.Errorf("2023-03-21: lag %.1f min, Yallop %v, Odeh %v, want the moon setting first", c.Lag, c.Yallop, c.Odeh)
+	}
+	//one count spans the sunset and the moonset scans
+	if last[0] != last[1] || last[1] != 2*stepCount(in.date(), in.date().Add(24*time.Hour), nightStep) {
+		t.Errorf("progress ended at %d of %d", last[0], last[1])
+	}
+
+	c, err = s.CalculateCrescentVisibility(context.Background(), time.Date(2023, 3, 22, 0, 0, 0, 0, mecca), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := time.Date(2023, 3, 22, 18, 32, 0, 0, mecca); math.Abs(c.Sunset.Sub(want).Minutes()) > 2 {
+		t.Errorf("sunset %v, want %v", c.Sunset, want)
+	}
+	if c.Lag < 40 || c.Lag > 60 || c.Odeh != OdehA {
+		t.Errorf("2023-03-22: lag %.1f min, q %.3f (%v), V %.2f (%v), want a naked eye crescent", c.Lag, c.YallopQ, c.Yallop, c.OdehV, c.Odeh)
+	}
+	if want := c.Sunset.Add(time.Duration(c.Lag*4/9*float64(time.Minute))); math.Abs(c.BestTime.Sub(want).Seconds()) > 1 {
+		t.Errorf("best time %v, want %v", c.BestTime, want)
+	}
+	if want := c.Arcv - (yallopArcv(c.Width) - 4.672); math.Abs(c.OdehV-want) > 1e-9 || c.Arcl < c.Arcv {
+		t.Errorf("Odeh V %.4f, want %.4f, arc of light %.3f, arc of vision %.3f", c.OdehV, want, c.Arcl, c.Arcv)
+	}
+
+	//Yallop q takes the geocentric arc of vision, higher than the topocentric one by about the lunar
+	//parallax: near the A/B boundary the crescent is easily visible, with the topocentric arc of
+	//vision it would fall into category B
+	if d := c.ArcvGeo - c.Arcv; d < 0.9 || d > 1.02 {
+		t.Errorf("geocentric arc of vision %.3f, topocentric %.3f, want a difference of the lunar parallax", c.ArcvGeo, c.Arcv)
+	}
+	if want := (c.ArcvGeo - yallopArcv(c.Width)) / 10; math.Abs(c.YallopQ-want) > 1e-9 || c.Yallop != YallopA ||
+		yallopCategory((c.Arcv-yallopArcv(c.Width))/10) != YallopB {
+		t.Errorf("Yallop q %.4f (%v), want %.4f in category A", c.YallopQ, c.Yallop, want)
+	}
+}
+
+//category boundaries of the Yallop q value (Yallop 1997, NAO Technical Note 69)
+func TestYallopCategory(t *testing.T) {
+	for _, c := range []struct {
+		q    float64
+		want YallopCategory
+	}{
+		{0.217, YallopA}, {0.216, YallopB}, {-0.013, YallopB}, {-0.014, YallopC}, {-0.159, YallopC}, {-0.160, YallopD},
+		{-0.231, YallopD}, {-0.232, YallopE}, {-0.292, YallopE}, {-0.293, YallopF},
+	} {
+		if got := yallopCategory(c.q); got != c.want {
+			t.Errorf("q %.3f: category %v, want %v", c.q, got, c.want)
+		}
+	}
+	//the best time arc of vision for a crescent of zero width is 11.8371 degrees
+	if got := yallopArcv(0); got != 11.8371 {
+		t.Errorf("arc of vision at zero width %g", got)
+	}
+}
@@ -0,0 +1,173 @@
+package sampa
+
+import (
+	"context"
+	"math"
+	"time"
+)
+
+// SkyKind defines the darkness of the sky by the sun elevation
+type SkyKind uint32
+
+// enumeration for the twilight and darkness periods of a night
+const (
+	CivilTwilight        SkyKind = 0 //upper sun limb below the horizon, sun center above -6 degrees
+	NauticalTwilight     SkyKind = 1 //sun center from -6 to -12 degrees
+	AstronomicalTwilight SkyKind = 2 //sun center from -12 to -18 degrees
+	Darkness             SkyKind = 3 //sun center below -18 degrees
+	daylight             SkyKind = 4 //upper sun limb above the horizon, not part of a night
+)
+
+const nightStep = 10 * time.Minute //sampling step of the sun and moon elevations within a night
+
+// NightInterval is a period of a night with constant sky darkness and moon visibility
+type NightInterval struct {
+	Start               time.Time
+	End                 time.Time
+	Kind                SkyKind
+	MoonUp              bool    //upper moon limb above the horizon
+	IlluminatedFraction float64 //illuminated fraction of the moon disk at the middle of the interval [fraction]
+}
+
+// Night is the sequence of intervals from the local noon of Date to the local noon of the next day
+// during which the sun is below the horizon, the sun may not set near the poles
+type Night struct {
+	Date      time.Time //local midnight at the beginning of the evening date
+	Intervals []NightInterval
+}
+
+//sky darkness and moon visibility at one instant
+type nightState struct {
+	kind   SkyKind
+	moonUp bool
+}
+
+//sky kind of the topocentric sun elevation angle (corrected) e and the sun radius rs [degrees]
+func skyKind(e float64, rs float64) SkyKind {
+	switch {
+	case e > -rs:
+		return daylight
+	case e > -6:
+		return CivilTwilight
+	case e > -12:
+		return NauticalTwilight
+	case e > -18:
+		return AstronomicalTwilight
+	}
+	return Darkness
+}
+
+func (s *sampa) nightStateAt(t time.Time) (nightState, error) {
+	s.spaData.SetDate(t)
+	err := s.Calculate()
+	return nightState{kind: skyKind(s.sunE, s.rs), moonUp: s.mpaData.e > -s.rm}, err
+}
+
+//first change of the night state between a and b to one second, b if the state is constant
+func (s *sampa) nightStateChange(a time.Time, b time.Time, sa nightState) (time.Time, nightState, error) {
+	changed := func(t time.Time) (float64, error) {
+		st, err := s.nightStateAt(t)
+		if st != sa {
+			return 1, err
+		}
+		return -1, err
+	}
+	t, err := searchRoot(changed, a, b, -1)
+	if err != nil {
+		return t, sa, err
+	}
+	st, err := s.nightStateAt(t)
+	return t, st, err
+}
+
+func (s *sampa) illuminatedFractionAt(t time.Time) (float64, error) {
+	s.spaData.SetDate(t)
+	if err := s.Calculate(); err != nil {
+		return 0, err
+	}
+	i, _ := s.moonPhaseAngle(s.spaData.GetAlphaPrime(), s.spaData.GetDeltaPrime(), s.mpaData.alphaPrime, s.mpaData.deltaPrime, s.spaData.GetR(), s.mpaData.capDelta)
+	return (1 + math.Cos(s.deg2rad(i))) / 2, nil
+}
+
+//intervals of the night from a to b sampled in steps of nightStep, daylight is left out
+func (s *sampa) nightIntervals(ctx context.Context, a time.Time, b time.Time, pc *progressCounter) ([]NightInterval, error) {
+	var intervals []NightInterval
+	start := a
+	state, err := s.nightStateAt(a)
+	if err != nil {
+		return nil, err
+	}
+	closeInterval := func(end time.Time) error {
+		if state.kind == daylight || !end.After(start) {
+			return nil
+		}
+		f, err := s.illuminatedFractionAt(start.Add(end.Sub(start) / 2).Truncate(time.Second))
+		intervals = append(intervals, NightInterval{Start: start, End: end, Kind: state.kind, MoonUp: state.moonUp, IlluminatedFraction: f})
+		return err
+	}
+
+	for t := a; t.Before(b); {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		next := t.Add(nightStep)
+		if next.After(b) {
+			next = b
+		}
+		nextState, err := s.nightStateAt(next)
+		if err != nil {
+			return nil, err
+		}
+		pc.add(1)
+		//more than one change within a step is refined change by change
+		for nextState != state {
+			change, changeState, err := s.nightStateChange(t, next, state)
+			if err != nil {
+				return nil, err
+			}
+			if err := closeInterval(change); err != nil {
+				return nil, err
+			}
+			t, start, state = change, change, changeState
+		}
+		t = next
+	}
+	return intervals, closeInterval(b)
+}
+
+///////////////////////////////////////////////////////////////////////////////////////////
+// Find the twilight, darkness and moon visibility intervals of every night from start to
+// end. A night runs from local noon to local noon of the next day in the location of start,
+// the first night begins on the date of start and the last one on the date of end. The sun
+// and moon elevations are corrected by the selected refraction model, sunset and moonset
+// are the upper limbs at the horizon and the twilight boundaries refer to the sun center.
+// The elevations are sampled every ten minutes, each change is refined to one second. The
+// date of the spa data and the SAMPA outputs are restored afterwards. Progress counts the
+// samples.
+///////////////////////////////////////////////////////////////////////////////////////////
+func (s *sampa) CalculateNights(ctx context.Context, start time.Time, end time.Time, progress Progress) (nights []Night, err error) {
+	defer restoreDate(s, &err)()
+	function := s.function
+	defer func() {
+		s.function = function
+	}()
+	s.function = SampaNoIrr
+
+	loc := start.Location()
+	first := time.Date(start.Year(), start.Month(), start.Day(), 12, 0, 0, 0, loc)
+	last := time.Date(end.Year(), end.Month(), end.Day(), 12, 0, 0, 0, loc)
+	total := 0
+	for noon := first; !noon.After(last); noon = noon.AddDate(0, 0, 1) {
+		total += int((noon.AddDate(0, 0, 1).Sub(noon) + nightStep - 1) / nightStep)
+	}
+	pc := newProgressCounter(progress, total)
+
+	for noon := first; !noon.After(last); noon = noon.AddDate(0, 0, 1) {
+		intervals, err := s.nightIntervals(ctx, noon, noon.AddDate(0, 0, 1), pc)
+		if err != nil {
+			return nil, err
+		}
+		nights = append(nights, Night{Date: time.Date(noon.Year(), noon.Month(), noon.Day(), 0, 0, 0, 0, loc), Intervals: intervals})
+	}
+	return nights, nil
+}
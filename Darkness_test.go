@@ -0,0 +1,84 @@
+package sampa
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+)
+
+//sun elevation from the low precision formulas of the Astronomical Almanac (section C, about
+//0.01 degrees from 1950 to 2050), without refraction [degrees]
+func almanacSunElevation(t time.Time, latitude float64, longitude float64) float64 {
+	rad := math.Pi / 180
+	n := float64(t.Unix())/86400 + 2440587.5 - 2451545.0
+	l := 280.460 + 0.9856474*n
+	g := (357.528 + 0.9856003*n) * rad
+	lamda := (l + 1.915*math.Sin(g) + 0.020*math.Sin(2*g)) * rad
+	epsilon := (23.439 - 0.0000004*n) * rad
+	alpha := math.Atan2(math.Cos(epsilon)*math.Sin(lamda), math.Cos(lamda))
+	delta := math.Asin(math.Sin(epsilon) * math.Sin(lamda))
+	h := (280.46061837+360.98564736629*n+longitude)*rad - alpha
+	phi := latitude * rad
+	return math.Asin(math.Sin(phi)*math.Sin(delta)+math.Cos(phi)*math.Cos(delta)*math.Cos(h)) / rad
+}
+
+//instant within 30 minutes of t at which the almanac sun elevation crosses elevation
+func almanacCrossing(t time.Time, elevation float64, latitude float64, longitude float64) time.Time {
+	f := func(t time.Time) (float64, error) {
+		return almanacSunElevation(t, latitude, longitude) - elevation, nil
+	}
+	a, b := t.Add(-30*time.Minute), t.Add(30*time.Minute)
+	fa, _ := f(a)
+	crossing, _ := searchRoot(f, a, b, fa)
+	return crossing
+}
+
+//sunset, twilight boundaries and sunrise of the night of 2016-11-14 (JST) at the reference site
+//against the almanac definitions: upper limb at -0.833 degrees with standard refraction, sun
+//center at -6, -12 and -18 degrees
+func TestNights(t *testing.T) {
+	in := referenceInput()
+	in.DeltaT = 68.2
+	in.setDate(time.Date(2016, 11, 14, 3, 0, 0, 0, time.UTC))
+	s, err := in.sampa()
+	if err != nil {
+		t.Fatal(err)
+	}
+	jst := time.FixedZone("JST", 9*3600)
+	date := time.Date(2016, 11, 14, 0, 0, 0, 0, jst)
+	progress, last := monotonicProgress(t)
+	nights, err := s.CalculateNights(context.Background(), date, date, progress)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(nights) != 1 || !nights[0].Date.Equal(date) || last[0] != last[1] {
+		t.Fatalf("nights %+v, progress %d of %d", nights, last[0], last[1])
+	}
+	kinds := []SkyKind{CivilTwilight, NauticalTwilight, AstronomicalTwilight, Darkness, AstronomicalTwilight, NauticalTwilight, CivilTwilight}
+	intervals := nights[0].Intervals
+	if len(intervals) != len(kinds) {
+		t.Fatalf("%d intervals, want %d", len(intervals), len(kinds))
+	}
+	thresholds := map[SkyKind]float64{CivilTwilight: -0.833, NauticalTwilight: -6, AstronomicalTwilight: -12, Darkness: -18}
+	for i, iv := range intervals {
+		if iv.Kind != kinds[i] || !iv.MoonUp || iv.IlluminatedFraction < 0.99 {
+			t.Errorf("interval %d: %+v, want %v with the full moon up", i, iv, kinds[i])
+		}
+		//the upper elevation limit of a twilight: the start in the evening, the end in the morning
+		boundary := iv.Start
+		if i > len(intervals)/2 {
+			boundary = iv.End
+		}
+		want := almanacCrossing(boundary, thresholds[iv.Kind], in.Latitude, in.Longitude)
+		if d := boundary.Sub(want); math.Abs(d.Minutes()) > 1 {
+			t.Errorf("interval %d boundary %v, almanac %v", i, boundary.In(jst), want.In(jst))
+		}
+	}
+	if end := intervals[len(intervals)/2].End; math.Abs(end.Sub(almanacCrossing(end, -18, in.Latitude, in.Longitude)).Minutes()) > 1 {
+		t.Errorf("end of darkness %v", end.In(jst))
+	}
+	if got := s.GetSpaData().GetDate(); !got.Equal(in.date()) {
+		t.Errorf("spa date %v not restored", got)
+	}
+}
@@ -0,0 +1,279 @@
+package sampa
+
+import (
+	"context"
+	"math"
+	"sort"
+	"time"
+)
+
+// EphemerisQuantity defines a geocentric quantity interpolated by an Ephemeris
+type EphemerisQuantity uint32
+
+// enumeration for the quantities of an ephemeris
+const (
+	EphemerisMoonLongitude     EphemerisQuantity = 0 //moon longitude [degrees]
+	EphemerisMoonLatitude      EphemerisQuantity = 1 //moon latitude [degrees]
+	EphemerisMoonDistance      EphemerisQuantity = 2 //distance between the centers of earth and moon [kilometers]
+	EphemerisSunLongitude      EphemerisQuantity = 3 //apparent sun longitude [degrees]
+	EphemerisSunDistance       EphemerisQuantity = 4 //earth radius vector [Astronomical Units, AU]
+	EphemerisNutationLongitude EphemerisQuantity = 5 //nutation in longitude [degrees]
+	EphemerisObliquity         EphemerisQuantity = 6 //true obliquity of the ecliptic [degrees]
+)
+
+const ephemerisQuantities = 7
+
+// Ephemeris interface defines Chebyshev polynomials fitted to the geocentric moon and sun quantities
+// over consecutive segments, usable by the MPA instead of the periodic term series (see SetEphemeris)
+type Ephemeris interface {
+	//first covered Julian Ephemeris Century
+	GetJceStart() float64
+	//last covered Julian Ephemeris Century
+	GetJceEnd() float64
+	//degree of the polynomials
+	GetDegree() int
+	//number of fitted segments
+	GetSegments() int
+	//maximum deviation of the polynomials from the series at check points between the fitted samples
+	GetMaxError(EphemerisQuantity) float64
+	//true if the Julian Ephemeris Century is covered
+	Covers(jce float64) bool
+	//interpolated quantity at the Julian Ephemeris Century, NaN if not covered
+	Evaluate(q EphemerisQuantity, jce float64) float64
+}
+
+type chebyshevSegment struct {
+	jceStart float64
+	jceEnd   float64
+	coeffs   [ephemerisQuantities][]float64
+}
+
+type ephemeris struct {
+	s        *sampa //sampa the polynomials were fitted with, limits the angles
+	degree   int
+	segments []chebyshevSegment
+	maxError [ephemerisQuantities]float64
+}
+
+func (e *ephemeris) GetJceStart() float64 {
+	return e.segments[0].jceStart
+}
+
+func (e *ephemeris) GetJceEnd() float64 {
+	return e.segments[len(e.segments)-1].jceEnd
+}
+
+func (e *ephemeris) GetDegree() int {
+	return e.degree
+}
+
+func (e *ephemeris) GetSegments() int {
+	return len(e.segments)
+}
+
+func (e *ephemeris) GetMaxError(q EphemerisQuantity) float64 {
+	if q >= ephemerisQuantities {
+		return math.NaN()
+	}
+	return e.maxError[q]
+}
+
+func (e *ephemeris) Covers(jce float64) bool {
+	return jce >= e.GetJceStart() && jce <= e.GetJceEnd()
+}
+
+func (e *ephemeris) Evaluate(q EphemerisQuantity, jce float64) float64 {
+	if q >= ephemerisQuantities || !e.Covers(jce) {
+		return math.NaN()
+	}
+	i := sort.Search(len(e.segments)-1, func(i int) bool { return e.segments[i].jceEnd >= jce })
+	seg := &e.segments[i]
+	v := chebyshevValue(seg.coeffs[q], 2*(jce-seg.jceStart)/(seg.jceEnd-seg.jceStart)-1)
+	switch q {
+	case EphemerisMoonLongitude, EphemerisMoonLatitude, EphemerisSunLongitude:
+		return e.s.limitDegrees(v)
+	}
+	return v
+}
+
+//value of a Chebyshev series at x in [-1, 1] (Clenshaw recurrence)
+func chebyshevValue(coeffs []float64, x float64) float64 {
+	var b1, b2 float64
+	for k := len(coeffs) - 1; k > 0; k-- {
+		b1, b2 = 2*x*b1-b2+coeffs[k], b1
+	}
+	return x*b1 - b2 + coeffs[0]
+}
+
+//least squares coefficients of a Chebyshev series of degree for the samples y at x in [-1, 1],
+//solved by Householder QR decomposition
+func chebyshevFit(x []float64, y []float64, degree int) []float64 {
+	m, n := len(x), degree+1
+	a := make([][]float64, m)
+	for i := range a {
+		a[i] = make([]float64, n)
+		a[i][0] = 1
+		if n > 1 {
+			a[i][1] = x[i]
+		}
+		for k := 2; k < n; k++ {
+			a[i][k] = 2*x[i]*a[i][k-1] - a[i][k-2]
+		}
+	}
+	b := append([]float64(nil), y...)
+	for k := 0; k < n; k++ {
+		norm := 0.
+		for i := k; i < m; i++ {
+			norm += a[i][k] * a[i][k]
+		}
+		norm = math.Sqrt(norm)
+		if a[k][k] > 0 {
+			norm = -norm
+		}
+		v := make([]float64, m)
+		for i := k; i < m; i++ {
+			v[i] = a[i][k]
+		}
+		v[k] -= norm
+		vv := 0.
+		for i := k; i < m; i++ {
+			vv += v[i] * v[i]
+		}
+		if vv == 0 {
+			continue
+		}
+		for j := k; j < n; j++ {
+			dot := 0.
+			for i := k; i < m; i++ {
+				dot += v[i] * a[i][j]
+			}
+			for i := k; i < m; i++ {
+				a[i][j] -= 2 * dot / vv * v[i]
+			}
+		}
+		dot := 0.
+		for i := k; i < m; i++ {
+			dot += v[i] * b[i]
+		}
+		for i := k; i < m; i++ {
+			b[i] -= 2 * dot / vv * v[i]
+		}
+	}
+	coeffs := make([]float64, n)
+	for k := n - 1; k >= 0; k-- {
+		sum := b[k]
+		for j := k + 1; j < n; j++ {
+			sum -= a[k][j] * coeffs[j]
+		}
+		coeffs[k] = sum / a[k][k]
+	}
+	return coeffs
+}
+
+//wrap an angle difference into [-180, 180) degrees
+func wrapDegrees(degrees float64) float64 {
+	return degrees - 360*math.Floor((degrees+180)/360)
+}
+
+//geocentric quantities of the series at the current spa date, the moon position of the periodic term series
+func (s *sampa) ephemerisSample() (float64, [ephemerisQuantities]float64, error) {
+	var v [ephemerisQuantities]float64
+	err := s.spaData.Calculate()
+	if err != nil {
+		return 0, v, err
+	}
+	jce := s.spaData.GetJce()
+	lamdaPrime, beta, capDelta := s.moonSeriesPosition(jce)
+
+	v[EphemerisMoonLongitude] = lamdaPrime
+	v[EphemerisMoonLatitude] = wrapDegrees(beta)
+	v[EphemerisMoonDistance] = capDelta
+	v[EphemerisSunLongitude] = s.spaData.GetLamda()
+	v[EphemerisSunDistance] = s.spaData.GetR()
+	v[EphemerisNutationLongitude] = s.spaData.GetDelPsi()
+	v[EphemerisObliquity] = s.spaData.GetEpsilon()
+	return jce, v, nil
+}
+
+///////////////////////////////////////////////////////////////////////////////////////////
+// Fit Chebyshev polynomials of degree to the geocentric moon and sun quantities over
+// consecutive segments of span from start until end is covered. Each segment is fitted
+// by least squares to samples at whole seconds (the date resolution of spa), the error
+// bounds are the largest deviations at check points between the samples. The date of the
+// spa data and the SAMPA outputs are restored afterwards. Progress counts the segments.
+///////////////////////////////////////////////////////////////////////////////////////////
+func (s *sampa) CalculateEphemeris(ctx context.Context, start time.Time, end time.Time, span time.Duration, degree int, progress Progress) (eph Ephemeris, err error) {
+	err = checkRange("degree", float64(degree), 1, 32, ErrInvalidOption)
+	if err != nil {
+		return nil, err
+	}
+	samples := 3 * (degree + 1)
+	minSpan := time.Duration(2*(samples-1)) * time.Second
+	err = checkRange("span", span.Seconds(), minSpan.Seconds(), math.Inf(1), ErrInvalidOption)
+	if err != nil {
+		return nil, err
+	}
+	if end.Before(start) {
+		return nil, &ValidationError{Field: "end", Value: float64(end.Unix()), Min: float64(start.Unix()), Max: math.Inf(1), Err: ErrInvalidOption}
+	}
+
+	sp := s.spaData
+	defer restoreDate(s, &err)()
+
+	e := ephemeris{s: s, degree: degree}
+	segments := 0
+	for segStart := start; segments == 0 || segStart.Before(end); segStart = segStart.Add(span) {
+		segments++
+	}
+	pc := newProgressCounter(progress, segments)
+	for segStart := start; ; segStart = segStart.Add(span) {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		//sample times at whole seconds, check times between them
+		at := func(k int, of int) time.Time {
+			return segStart.Add(time.Duration(math.Round(float64(k)*span.Seconds()/float64(of))) * time.Second)
+		}
+		jces := make([]float64, 0, samples)
+		values := make([][ephemerisQuantities]float64, 0, samples)
+		for k := 0; k < 2*samples-1; k++ {
+			sp.SetDate(at(k, 2*(samples-1)))
+			jce, v, err := s.ephemerisSample()
+			if err != nil {
+				return nil, err
+			}
+			jces = append(jces, jce)
+			values = append(values, v)
+		}
+
+		seg := chebyshevSegment{jceStart: jces[0], jceEnd: jces[len(jces)-1]}
+		x := make([]float64, len(jces))
+		for i, jce := range jces {
+			x[i] = 2*(jce-seg.jceStart)/(seg.jceEnd-seg.jceStart) - 1
+		}
+		//continuous longitudes within the segment
+		for _, q := range []EphemerisQuantity{EphemerisMoonLongitude, EphemerisSunLongitude} {
+			for i := 1; i < len(values); i++ {
+				values[i][q] = values[i-1][q] + wrapDegrees(values[i][q]-values[i-1][q])
+			}
+		}
+		for q := 0; q < ephemerisQuantities; q++ {
+			var fx, fy []float64
+			for i := 0; i < len(x); i += 2 {
+				fx = append(fx, x[i])
+				fy = append(fy, values[i][q])
+			}
+			seg.coeffs[q] = chebyshevFit(fx, fy, degree)
+			for i := 1; i < len(x); i += 2 {
+				d := math.Abs(chebyshevValue(seg.coeffs[q], x[i]) - values[i][q])
+				e.maxError[q] = math.Max(e.maxError[q], d)
+			}
+		}
+		e.segments = append(e.segments, seg)
+		pc.add(1)
+		if !segStart.Add(span).Before(end) {
+			break
+		}
+	}
+	return &e, nil
+}
@@ -0,0 +1,109 @@
+package sampa
+
+import (
+	"errors"
+	"fmt"
+	"math"
+)
+
+// sentinel errors wrapped by ValidationError, usable with errors.Is
+var (
+	ErrInvalidDate     = errors.New("invalid date")
+	ErrInvalidFunction = errors.New("invalid function")
+	ErrInvalidBird     = errors.New("invalid bird input")
+	ErrInvalidOption   = errors.New("invalid option")
+)
+
+// ValidationError describes an input value outside of its valid range
+type ValidationError struct {
+	Field string  //name of the input
+	Value float64 //given value
+	Min   float64 //lower limit of the valid range
+	Max   float64 //upper limit of the valid range
+	Err   error   //sentinel error of the input group
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("%v: %s = %g, valid range: %g to %g", e.Err, e.Field, e.Value, e.Min, e.Max)
+}
+
+func (e *ValidationError) Unwrap() error {
+	return e.Err
+}
+
+//returns a ValidationError if value is NaN or outside of [min, max]
+func checkRange(field string, value float64, min float64, max float64, err error) error {
+	if !(value >= min && value <= max) {
+		return &ValidationError{Field: field, Value: value, Min: min, Max: max, Err: err}
+	}
+	return nil
+}
+
+///////////////////////////////////////////////////////////////////////////////////////////
+// Validate the SAMPA inputs before computation: the date of the spa data within the range
+// of the moon position algorithm, the function code, the options and (if used) the Bird
+// model inputs
+///////////////////////////////////////////////////////////////////////////////////////////
+func (s *sampa) validate() error {
+	checks := []struct {
+		field    string
+		value    float64
+		min, max float64
+		err      error
+	}{
+		//the hour can only be set with the date and is always valid
+		{"year", float64(s.spaData.GetYear()), -2000, 6000, ErrInvalidDate},
+		{"month", float64(s.spaData.GetMonth()), 1, 12, ErrInvalidDate},
+		{"day", float64(s.spaData.GetDay()), 1, float64(daysInMonth(s.spaData.GetYear(), s.spaData.GetMonth())), ErrInvalidDate},
+		{"minute", float64(s.spaData.GetMinute()), 0, 59, ErrInvalidDate},
+		{"second", s.spaData.GetSecond(), 0, math.Nextafter(60, 0), ErrInvalidDate},
+		{"function", float64(s.function), SampaNoIrr, SampaAll, ErrInvalidFunction},
+		{"moonRadiusRatio", s.moonRadiusRatio, 0.2, 0.3, ErrInvalidOption},
+		{"refractionModel", float64(s.refractionModel), float64(RefractionSaemundsson), float64(RefractionRayTraced), ErrInvalidOption},
+		{"humidity", s.humidity, 0, 1, ErrInvalidOption},
+		{"lapseRate", s.lapseRate, 0, 0.1, ErrInvalidOption},
+	}
+	for _, c := range checks {
+		if err := checkRange(c.field, c.value, c.min, c.max, c.err); err != nil {
+			return err
+		}
+	}
+
+	if s.function == SampaAll {
+		if s.birdData == nil {
+			return fmt.Errorf("%w: missing Bird data for function SampaAll", ErrInvalidBird)
+		}
+		birdChecks := []struct {
+			field    string
+			value    float64
+			min, max float64
+		}{
+			{"ozone", s.birdData.GetOzone(), 0, 1},  //total ozone [atm-cm]
+			{"water", s.birdData.GetWater(), 0, 10}, //precipitable water [cm]
+			{"taua", s.birdData.GetTaua(), 0, 5},    //aerosol optical depth, up to dense smoke and dust storms
+			{"ba", s.birdData.GetBa(), 0, 1},        //forward scattering ratio of aerosols
+			{"albedo", s.birdData.GetAlbedo(), 0, 1},
+		}
+		for _, c := range birdChecks {
+			if err := checkRange(c.field, c.value, c.min, c.max, ErrInvalidBird); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+//number of days of a month, with the leap years of the Julian calendar before 1583 as in the SPA,
+//31 for an invalid month
+func daysInMonth(year int, month int) int {
+	switch month {
+	case 2:
+		if year%4 == 0 && (year < 1583 || year%100 != 0 || year%400 == 0) {
+			return 29
+		}
+		return 28
+	case 4, 6, 9, 11:
+		return 30
+	}
+	return 31
+}
@@ -0,0 +1,301 @@
+package sampa
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"errors"
+	"io"
+	"math"
+	"strconv"
+	"time"
+)
+
+// Column defines an output column of exported SAMPA time series
+type Column uint32
+
+// enumeration for the columns of exported time series
+const (
+	ColumnTime        Column = 0  //timestamp of the spa date
+	ColumnSunZenith   Column = 1  //topocentric sun zenith angle
+	ColumnSunAzimuth  Column = 2  //topocentric sun azimuth angle (eastward from north)
+	ColumnMoonZenith  Column = 3  //topocentric moon zenith angle
+	ColumnMoonAzimuth Column = 4  //topocentric moon azimuth angle (eastward from north)
+	ColumnEms         Column = 5  //angular distance between sun and moon centers
+	ColumnRs          Column = 6  //radius of sun disk
+	ColumnRm          Column = 7  //radius of moon disk
+	ColumnASul        Column = 8  //area of sun's unshaded lune
+	ColumnASulPct     Column = 9  //percent area of sun's unshaded lune [percent]
+	ColumnDni         Column = 10 //estimated direct normal irradiance
+	ColumnDniSul      Column = 11 //estimated direct normal irradiance from the SUL
+	ColumnGhi         Column = 12 //estimated global horizontal irradiance
+	ColumnGhiSul      Column = 13 //estimated global horizontal irradiance from the SUL
+	ColumnDhi         Column = 14 //estimated diffuse horizontal irradiance
+	ColumnDhiSul      Column = 15 //estimated diffuse horizontal irradiance from the SUL
+)
+
+// DefaultColumns lists all columns in their default order
+var DefaultColumns = []Column{ColumnTime, ColumnSunZenith, ColumnSunAzimuth, ColumnMoonZenith, ColumnMoonAzimuth,
+	ColumnEms, ColumnRs, ColumnRm, ColumnASul, ColumnASulPct, ColumnDni, ColumnDniSul, ColumnGhi, ColumnGhiSul,
+	ColumnDhi, ColumnDhiSul}
+
+// AngleUnit defines the unit of exported angles
+type AngleUnit uint32
+
+// enumeration for angle units
+const (
+	AngleDegrees AngleUnit = 0
+	AngleRadians AngleUnit = 1
+)
+
+// IrradianceUnit defines the unit of exported irradiances
+type IrradianceUnit uint32
+
+// enumeration for irradiance units
+const (
+	WattsPerSquareMeter     IrradianceUnit = 0
+	KilowattsPerSquareMeter IrradianceUnit = 1
+)
+
+// ExportOptions defines the columns, units and number format of exported time series
+type ExportOptions struct {
+	Columns        []Column       //columns in output order, DefaultColumns if empty
+	AngleUnit      AngleUnit      //unit of angles, areas are exported in the unit squared
+	IrradianceUnit IrradianceUnit //unit of irradiances
+	Precision      int            //digits after the decimal point, the shortest exact representation if not positive
+	TimeFormat     string         //layout of timestamps, time.RFC3339 if empty
+}
+
+type columnKind uint32
+
+const (
+	kindTime columnKind = iota
+	kindAngle
+	kindArea
+	kindPercent
+	kindIrradiance
+)
+
+var columnInfo = map[Column]struct {
+	name string
+	kind columnKind
+	get  func(Sampa) float64
+}{
+	ColumnTime:        {"time", kindTime, nil},
+	ColumnSunZenith:   {"sun_zenith", kindAngle, Sampa.GetSunZenith},
+	ColumnSunAzimuth:  {"sun_azimuth", kindAngle, func(s Sampa) float64 { return s.GetSpaData().GetAzimuth() }},
+	ColumnMoonZenith:  {"moon_zenith", kindAngle, func(s Sampa) float64 { return s.GetMpaData().GetZenith() }},
+	ColumnMoonAzimuth: {"moon_azimuth", kindAngle, func(s Sampa) float64 { return s.GetMpaData().GetAzimuth() }},
+	ColumnEms:         {"ems", kindAngle, Sampa.GetEms},
+	ColumnRs:          {"rs", kindAngle, Sampa.GetRs},
+	ColumnRm:          {"rm", kindAngle, Sampa.GetRm},
+	ColumnASul:        {"a_sul", kindArea, Sampa.GetASul},
+	ColumnASulPct:     {"a_sul_pct", kindPercent, Sampa.GetASulPct},
+	ColumnDni:         {"dni", kindIrradiance, Sampa.GetDni},
+	ColumnDniSul:      {"dni_sul", kindIrradiance, Sampa.GetDniSul},
+	ColumnGhi:         {"ghi", kindIrradiance, Sampa.GetGhi},
+	ColumnGhiSul:      {"ghi_sul", kindIrradiance, Sampa.GetGhiSul},
+	ColumnDhi:         {"dhi", kindIrradiance, Sampa.GetDhi},
+	ColumnDhiSul:      {"dhi_sul", kindIrradiance, Sampa.GetDhiSul},
+}
+
+// SeriesWriter writes one record per calculated instant of a Sampa
+type SeriesWriter interface {
+	//write the outputs of the last calculation of s
+	Write(s Sampa) error
+	//flush buffered records to the underlying writer
+	Flush() error
+}
+
+type seriesFormat struct {
+	opts      ExportOptions
+	precision int //precision of strconv.AppendFloat, -1 for the shortest exact representation
+}
+
+func newSeriesFormat(opts ExportOptions) (seriesFormat, error) {
+	if len(opts.Columns) == 0 {
+		opts.Columns = DefaultColumns
+	}
+	for _, c := range opts.Columns {
+		if _, ok := columnInfo[c]; !ok {
+			return seriesFormat{}, &ValidationError{Field: "column", Value: float64(c), Min: float64(ColumnTime), Max: float64(ColumnDhiSul), Err: ErrInvalidOption}
+		}
+	}
+	if opts.TimeFormat == "" {
+		opts.TimeFormat = time.RFC3339
+	}
+	precision := -1
+	if opts.Precision > 0 {
+		precision = opts.Precision
+	}
+	return seriesFormat{opts: opts, precision: precision}, nil
+}
+
+//value of a column converted to the selected units
+func (f *seriesFormat) value(c Column, s Sampa) float64 {
+	info := columnInfo[c]
+	v := info.get(s)
+	switch info.kind {
+	case kindAngle:
+		if f.opts.AngleUnit == AngleRadians {
+			v *= math.Pi / 180
+		}
+	case kindArea:
+		if f.opts.AngleUnit == AngleRadians {
+			v *= (math.Pi / 180) * (math.Pi / 180)
+		}
+	case kindIrradiance:
+		if f.opts.IrradianceUnit == KilowattsPerSquareMeter {
+			v /= 1000
+		}
+	}
+	return v
+}
+
+func (f *seriesFormat) appendColumn(buf []byte, c Column, s Sampa) []byte {
+	if c == ColumnTime {
+		return s.GetSpaData().GetDate().AppendFormat(buf, f.opts.TimeFormat)
+	}
+	return strconv.AppendFloat(buf, f.value(c, s), 'f', f.precision, 64)
+}
+
+type csvWriter struct {
+	seriesFormat
+	w      *csv.Writer
+	header bool
+	record []string
+	buf    []byte
+}
+
+// NewCSVWriter creates a SeriesWriter for comma separated values with a header row
+func NewCSVWriter(w io.Writer, opts ExportOptions) (SeriesWriter, error) {
+	f, err := newSeriesFormat(opts)
+	if err != nil {
+		return nil, err
+	}
+	return &csvWriter{seriesFormat: f, w: csv.NewWriter(w), record: make([]string, len(f.opts.Columns))}, nil
+}
+
+func (c *csvWriter) Write(s Sampa) error {
+	if !c.header {
+		for i, col := range c.opts.Columns {
+			c.record[i] = columnInfo[col].name
+		}
+		if err := c.w.Write(c.record); err != nil {
+			return err
+		}
+		c.header = true
+	}
+	for i, col := range c.opts.Columns {
+		c.buf = c.appendColumn(c.buf[:0], col, s)
+		c.record[i] = string(c.buf)
+	}
+	return c.w.Write(c.record)
+}
+
+func (c *csvWriter) Flush() error {
+	c.w.Flush()
+	return c.w.Error()
+}
+
+type jsonLinesWriter struct {
+	seriesFormat
+	w   *bufio.Writer
+	buf []byte
+}
+
+// NewJSONLinesWriter creates a SeriesWriter for JSON Lines, one object per record
+func NewJSONLinesWriter(w io.Writer, opts ExportOptions) (SeriesWriter, error) {
+	f, err := newSeriesFormat(opts)
+	if err != nil {
+		return nil, err
+	}
+	return &jsonLinesWriter{seriesFormat: f, w: bufio.NewWriter(w)}, nil
+}
+
+func (j *jsonLinesWriter) Write(s Sampa) error {
+	j.buf = append(j.buf[:0], '{')
+	for i, col := range j.opts.Columns {
+		if i > 0 {
+			j.buf = append(j.buf, ',')
+		}
+		j.buf = strconv.AppendQuote(j.buf, columnInfo[col].name)
+		j.buf = append(j.buf, ':')
+		if col == ColumnTime {
+			j.buf = strconv.AppendQuote(j.buf, s.GetSpaData().GetDate().Format(j.opts.TimeFormat))
+			continue
+		}
+		v := j.value(col, s)
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			j.buf = append(j.buf, "null"...)
+			continue
+		}
+		j.buf = strconv.AppendFloat(j.buf, v, 'f', j.precision, 64)
+	}
+	j.buf = append(j.buf, '}', '\n')
+	_, err := j.w.Write(j.buf)
+	return err
+}
+
+func (j *jsonLinesWriter) Flush() error {
+	return j.w.Flush()
+}
+
+//restore the date of the spa data and the outputs of s when the returned function is deferred by a batch
+//with the named error result err, which keeps the error of the batch or else takes that of the restoring
+func restoreDate(s Sampa, err *error) func() {
+	sp := s.GetSpaData()
+	date := sp.GetDate()
+	return func() {
+		sp.SetDate(date)
+		restoreErr := s.Calculate()
+		if *err == nil {
+			*err = restoreErr
+		}
+	}
+}
+
+///////////////////////////////////////////////////////////////////////////////////////////
+// Calculate s from start to end (inclusive) in steps and call fn after each calculation.
+// The date of the spa data and the outputs of s are restored afterwards, an error of the
+// restoring calculation is returned. Walking stops with the error of ctx when it is canceled
+// or its deadline is exceeded.
+///////////////////////////////////////////////////////////////////////////////////////////
+func Walk(ctx context.Context, s Sampa, start time.Time, end time.Time, step time.Duration, progress Progress, fn func(Sampa) error) (err error) {
+	if step <= 0 {
+		return &ValidationError{Field: "step", Value: step.Seconds(), Min: 1e-9, Max: math.Inf(1), Err: ErrInvalidOption}
+	}
+	sp := s.GetSpaData()
+	defer restoreDate(s, &err)()
+
+	pc := newProgressCounter(progress, stepCount(start, end, step))
+	for t := start; !t.After(end); t = t.Add(step) {
+		err = ctx.Err()
+		if err != nil {
+			return err
+		}
+		sp.SetDate(t)
+		err = s.Calculate()
+		if err != nil {
+			return err
+		}
+		err = fn(s)
+		if err != nil {
+			return err
+		}
+		pc.add(1)
+	}
+	return nil
+}
+
+// ExportSeries writes the outputs of s from start to end (inclusive) in steps to sw and flushes it
+func ExportSeries(ctx context.Context, sw SeriesWriter, s Sampa, start time.Time, end time.Time, step time.Duration, progress Progress) error {
+	if sw == nil {
+		return errors.New("missing series writer")
+	}
+	err := Walk(ctx, s, start, end, step, progress, sw.Write)
+	if err != nil {
+		return err
+	}
+	return sw.Flush()
+}
@@ -0,0 +1,222 @@
+package sampa
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+)
+
+var exportColumns = []Column{ColumnTime, ColumnEms, ColumnRs, ColumnRm, ColumnASul, ColumnASulPct}
+
+//exact outputs of the exported columns from start to end in steps of one minute
+func exportExpected(t *testing.T, s Sampa, start time.Time, end time.Time) [][]float64 {
+	var rows [][]float64
+	err := Walk(context.Background(), s, start, end, time.Minute, nil, func(s Sampa) error {
+		rows = append(rows, []float64{s.GetEms(), s.GetRs(), s.GetRm(), s.GetASul(), s.GetASulPct()})
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return rows
+}
+
+//the zero ExportOptions write the shortest representation that parses back to the same value
+func TestExportRoundTrip(t *testing.T) {
+	s, err := referenceInput().sampa()
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := referenceInput().date()
+	end := start.Add(4 * time.Minute)
+	want := exportExpected(t, s, start, end)
+	opts := ExportOptions{Columns: exportColumns}
+
+	var buf bytes.Buffer
+	w, err := NewCSVWriter(&buf, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ExportSeries(context.Background(), w, s, start, end, time.Minute, nil); err != nil {
+		t.Fatal(err)
+	}
+	records, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != len(want)+1 {
+		t.Fatalf("CSV has %d records, want %d", len(records), len(want)+1)
+	}
+	if records[0][1] != "ems" || records[0][5] != "a_sul_pct" {
+		t.Errorf("CSV header %v", records[0])
+	}
+	for i, record := range records[1:] {
+		if ts := start.Add(time.Duration(i) * time.Minute).Format(time.RFC3339); record[0] != ts {
+			t.Errorf("CSV row %d time %s, want %s", i, record[0], ts)
+		}
+		for j, field := range record[1:] {
+			got, err := strconv.ParseFloat(field, 64)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != want[i][j] {
+				t.Errorf("CSV row %d %s = %v, want %v", i, records[0][j+1], got, want[i][j])
+			}
+		}
+	}
+
+	buf.Reset()
+	w, err = NewJSONLinesWriter(&buf, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ExportSeries(context.Background(), w, s, start, end, time.Minute, nil); err != nil {
+		t.Fatal(err)
+	}
+	scanner := bufio.NewScanner(&buf)
+	i := 0
+	for ; scanner.Scan(); i++ {
+		var row struct {
+			Time    string  `json:"time"`
+			Ems     float64 `json:"ems"`
+			Rs      float64 `json:"rs"`
+			Rm      float64 `json:"rm"`
+			ASul    float64 `json:"a_sul"`
+			ASulPct float64 `json:"a_sul_pct"`
+		}
+		if err := json.Unmarshal(scanner.Bytes(), &row); err != nil {
+			t.Fatal(err)
+		}
+		got := []float64{row.Ems, row.Rs, row.Rm, row.ASul, row.ASulPct}
+		for j := range got {
+			if got[j] != want[i][j] {
+				t.Errorf("JSON line %d column %d = %v, want %v", i, j, got[j], want[i][j])
+			}
+		}
+	}
+	if i != len(want) {
+		t.Errorf("JSON Lines has %d lines, want %d", i, len(want))
+	}
+}
+
+func TestExportPrecision(t *testing.T) {
+	s, err := referenceInput().sampa()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	w, err := NewCSVWriter(&buf, ExportOptions{Columns: []Column{ColumnRs}, Precision: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Write(s); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if want := "rs\n" + strconv.FormatFloat(s.GetRs(), 'f', 3, 64) + "\n"; buf.String() != want {
+		t.Errorf("CSV %q, want %q", buf.String(), want)
+	}
+}
+
+func TestStepCount(t *testing.T) {
+	start := referenceInput().date()
+	tests := []struct {
+		end  time.Time
+		step time.Duration
+		want int
+	}{
+		{start, time.Minute, 1},
+		{start.Add(59 * time.Second), time.Minute, 1},
+		{start.Add(time.Hour), time.Minute, 61},
+		{start.Add(-time.Minute), time.Minute, 0},
+		{start.Add(time.Hour), 0, 0},
+	}
+	for _, test := range tests {
+		if got := stepCount(start, test.end, test.step); got != test.want {
+			t.Errorf("stepCount(%v, %v) = %d, want %d", test.end.Sub(start), test.step, got, test.want)
+		}
+	}
+}
+
+//an error of the calculation restoring the outputs is returned by the walk
+func TestWalkRestoreError(t *testing.T) {
+	s, err := referenceInput().sampa()
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := referenceInput().date().Add(time.Hour)
+	ratio := s.GetMoonRadiusRatio()
+	err = Walk(context.Background(), s, start, start, time.Minute, nil, func(s Sampa) error {
+		s.SetMoonRadiusRatio(2.7)
+		return nil
+	})
+	if !errors.Is(err, ErrInvalidOption) {
+		t.Errorf("Walk() = %v, want %v", err, ErrInvalidOption)
+	}
+	if !s.GetSpaData().GetDate().Equal(referenceInput().date()) {
+		t.Errorf("spa date %v not restored", s.GetSpaData().GetDate())
+	}
+
+	//the error of the walk takes precedence
+	s.SetMoonRadiusRatio(ratio)
+	stop := errors.New("stop")
+	err = Walk(context.Background(), s, start, start, time.Minute, nil, func(s Sampa) error {
+		s.SetMoonRadiusRatio(2.7)
+		return stop
+	})
+	if err != stop {
+		t.Errorf("Walk() = %v, want %v", err, stop)
+	}
+}
+
+//a walk canceled from the progress callback stops after the current step and restores the date
+func TestWalkCancel(t *testing.T) {
+	s, err := referenceInput().sampa()
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := referenceInput().date().Add(time.Hour)
+	end := start.Add(time.Hour)
+	//progress that cancels the returned context after the third step
+	cancelAfterThree := func() (context.Context, Progress) {
+		ctx, cancel := context.WithCancel(context.Background())
+		return ctx, func(done int, total int) {
+			if done == 3 {
+				cancel()
+			}
+		}
+	}
+
+	steps := 0
+	ctx, progress := cancelAfterThree()
+	err = Walk(ctx, s, start, end, time.Minute, progress, func(s Sampa) error {
+		steps++
+		return nil
+	})
+	if !errors.Is(err, context.Canceled) || steps != 3 {
+		t.Errorf("Walk() = %v after %d steps, want %v after 3", err, steps, context.Canceled)
+	}
+	if !s.GetSpaData().GetDate().Equal(referenceInput().date()) {
+		t.Errorf("Walk: spa date %v not restored", s.GetSpaData().GetDate())
+	}
+
+	w, err := NewJSONLinesWriter(&bytes.Buffer{}, ExportOptions{Columns: exportColumns})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, progress = cancelAfterThree()
+	if err := ExportSeries(ctx, w, s, start, end, time.Minute, progress); !errors.Is(err, context.Canceled) {
+		t.Errorf("ExportSeries() = %v, want %v", err, context.Canceled)
+	}
+	if !s.GetSpaData().GetDate().Equal(referenceInput().date()) {
+		t.Errorf("ExportSeries: spa date %v not restored", s.GetSpaData().GetDate())
+	}
+}
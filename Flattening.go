@@ -0,0 +1,57 @@
+package sampa
+
+import (
+	"math"
+)
+
+const flattenedAreaSteps = 2000 //number of integration steps across the sun disk for the flattened sul area
+
+//vertical radius of a disk of radius r [degrees] centered at the topocentric elevation angle (uncorrected) e0 [degrees],
+//half the apparent distance between its refracted upper and lower limbs [degrees]
+func (s *sampa) verticalDiskRadius(e0 float64, r float64) float64 {
+	return r + (s.refractionCorrection(e0+r)-s.refractionCorrection(e0-r))/2
+}
+
+//apparent offset of the moon center from the sun center in the observer's horizontal plane,
+//x towards increasing azimuth and y towards the zenith [degrees]
+func (s *sampa) horizontalOffset(eSun float64, azmSun float64, eMoon float64, azmMoon float64) (x float64, y float64) {
+	es := s.deg2rad(eSun)
+	em := s.deg2rad(eMoon)
+	dAzm := s.deg2rad(azmMoon - azmSun)
+
+	x = s.rad2deg(math.Cos(em) * math.Sin(dAzm))
+	y = s.rad2deg(math.Sin(em)*math.Cos(es) - math.Cos(em)*math.Sin(es)*math.Cos(dAzm))
+	return x, y
+}
+
+///////////////////////////////////////////////////////////////////////////////////////////
+// Area of the sun's unshaded lune for vertically flattened disks: both disks are ellipses
+// with horizontal radii rs, rm and vertical radii rsVert, rmVert, the moon center is offset
+// by x, y from the sun center. The overlap is integrated along the horizontal axis.
+///////////////////////////////////////////////////////////////////////////////////////////
+func (s *sampa) sulAreaFlattened(x float64, y float64, rs float64, rsVert float64, rm float64, rmVert float64, aSul *float64, aSulPct *float64) {
+	sunArea := math.Pi * rs * rsVert
+	ai := 0.
+
+	lo := math.Max(-rs, x-rm)
+	hi := math.Min(rs, x+rm)
+	if lo < hi && math.Abs(y) < rsVert+rmVert {
+		step := (hi - lo) / flattenedAreaSteps
+		for i := 0; i < flattenedAreaSteps; i++ {
+			u := lo + (float64(i)+0.5)*step
+			hs := rsVert * math.Sqrt(math.Max(0, 1-(u/rs)*(u/rs)))
+			hm := rmVert * math.Sqrt(math.Max(0, 1-((u-x)/rm)*((u-x)/rm)))
+			chord := math.Min(hs, y+hm) - math.Max(-hs, y-hm)
+			if chord > 0 {
+				ai += chord * step
+			}
+		}
+	}
+
+	*aSul = sunArea - ai
+
+	if *aSul < 0 {
+		*aSul = 0
+	}
+	*aSulPct = *aSul * 100.0 / sunArea
+}
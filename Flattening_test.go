@@ -0,0 +1,65 @@
+package sampa
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+//partial eclipse at sunrise, the sun four degrees above the horizon
+func sunriseEclipseInput() goldenInput {
+	in := referenceInput()
+	in.setDate(time.Date(2021, 6, 10, 9, 0, 0, 0, time.UTC))
+	in.Latitude = 44.65
+	in.Longitude = -63.57
+	in.DeltaT = 69.4
+	in.Elevation = 250
+	return in
+}
+
+func TestFlattenedAreaCircular(t *testing.T) {
+	si, err := sunriseEclipseInput().sampa()
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := si.(*sampa)
+	//circular disks: the integrated area matches the closed form of the original SAMPA code
+	for _, ems := range []float64{0, 0.1, 0.3, 0.5, 0.6} {
+		var aSul, want, got float64
+		s.sulArea(ems, s.rs, s.rm, &aSul, &want)
+		s.sulAreaFlattened(ems*0.6, ems*0.8, s.rs, s.rs, s.rm, s.rm, &aSul, &got)
+		if math.Abs(got-want) > 1e-3 {
+			t.Errorf("ems = %g: flattened area %.6f%%, circular %.6f%%", ems, got, want)
+		}
+	}
+
+	x, y := s.horizontalOffset(s.sunE, s.spaData.GetAzimuth(), s.mpaData.GetE(), s.mpaData.GetAzimuth())
+	if d := math.Hypot(x, y); math.Abs(d-s.ems) > 1e-4 {
+		t.Errorf("horizontal offset %.6f, ems %.6f", d, s.ems)
+	}
+	if got := s.verticalDiskRadius(45, s.rs); math.Abs(got-s.rs) > 5e-4 {
+		t.Errorf("vertical radius at 45 degrees = %.6f, rs = %.6f", got, s.rs)
+	}
+}
+
+func TestDiskFlattening(t *testing.T) {
+	s, err := sunriseEclipseInput().sampa()
+	if err != nil {
+		t.Fatal(err)
+	}
+	circular := s.GetASulPct()
+	if s.GetRsVert() != s.GetRs() || s.GetRmVert() != s.GetRm() {
+		t.Errorf("flattening disabled: rsVert = %g, rmVert = %g, want %g, %g", s.GetRsVert(), s.GetRmVert(), s.GetRs(), s.GetRm())
+	}
+
+	s.SetDiskFlattening(true)
+	if err := s.Calculate(); err != nil {
+		t.Fatal(err)
+	}
+	if s.GetRsVert() >= s.GetRs() || s.GetRmVert() >= s.GetRm() {
+		t.Errorf("sunrise: rsVert = %g, rmVert = %g, want below rs = %g, rm = %g", s.GetRsVert(), s.GetRmVert(), s.GetRs(), s.GetRm())
+	}
+	if circular <= 0 || circular >= 100 || math.Abs(s.GetASulPct()-circular) < 0.1 {
+		t.Errorf("sunrise: flattened area %.6f%%, circular %.6f%%", s.GetASulPct(), circular)
+	}
+}
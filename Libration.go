@@ -0,0 +1,112 @@
+package sampa
+
+import (
+	"math"
+)
+
+const (
+	moonEquatorInclination = 1.54242     //inclination of the mean lunar equator to the ecliptic [degrees]
+	astronomicalUnit       = 149597870.7 //astronomical unit [kilometers]
+)
+
+// Libration interface defines the geocentric optical libration and orientation of the moon
+type Libration interface {
+	//optical libration in longitude, selenographic longitude of the sub-earth point [degrees]
+	GetLongitude() float64
+	//optical libration in latitude, selenographic latitude of the sub-earth point [degrees]
+	GetLatitude() float64
+	//position angle of the moon's axis of rotation, eastward from celestial north [degrees]
+	GetPositionAngle() float64
+	//selenographic longitude of the sub-solar point [degrees]
+	GetSubSolarLongitude() float64
+	//selenographic latitude of the sub-solar point [degrees]
+	GetSubSolarLatitude() float64
+	//selenographic colongitude of the sun, longitude of the morning terminator [degrees]
+	GetColongitude() float64
+}
+
+type libration struct {
+	longitude         float64 //optical libration in longitude [degrees]
+	latitude          float64 //optical libration in latitude [degrees]
+	positionAngle     float64 //position angle of the axis of rotation [degrees]
+	subSolarLongitude float64 //selenographic longitude of the sub-solar point [degrees]
+	subSolarLatitude  float64 //selenographic latitude of the sub-solar point [degrees]
+	colongitude       float64 //selenographic colongitude of the sun [degrees]
+}
+
+func (l *libration) GetLongitude() float64 {
+	return l.longitude
+}
+
+func (l *libration) GetLatitude() float64 {
+	return l.latitude
+}
+
+func (l *libration) GetPositionAngle() float64 {
+	return l.positionAngle
+}
+
+func (l *libration) GetSubSolarLongitude() float64 {
+	return l.subSolarLongitude
+}
+
+func (l *libration) GetSubSolarLatitude() float64 {
+	return l.subSolarLatitude
+}
+
+func (l *libration) GetColongitude() float64 {
+	return l.colongitude
+}
+
+//selenographic longitude and latitude of the point below a body at the mean ecliptic longitude lamda
+//and latitude beta, omega is the longitude of the ascending node and f the argument of latitude [degrees]
+func (s *sampa) selenographicPosition(lamda float64, beta float64, omega float64, f float64) (float64, float64) {
+	i := s.deg2rad(moonEquatorInclination)
+	w := s.deg2rad(lamda - omega)
+	betaRad := s.deg2rad(beta)
+	a := math.Atan2(math.Sin(w)*math.Cos(betaRad)*math.Cos(i)-math.Sin(betaRad)*math.Sin(i), math.Cos(w)*math.Cos(betaRad))
+	longitude := wrapDegrees(s.rad2deg(a) - f)
+	latitude := s.rad2deg(math.Asin(-math.Sin(w)*math.Cos(betaRad)*math.Sin(i) - math.Sin(betaRad)*math.Cos(i)))
+	return longitude, latitude
+}
+
+//position angle of the moon's axis of rotation from the apparent right ascension alpha of the moon,
+//the selenographic latitude b of the earth, the longitude of the ascending node omega, the nutation in
+//longitude delPsi and the true obliquity of the ecliptic epsilon [degrees]
+func (s *sampa) moonAxisPositionAngle(alpha float64, b float64, omega float64, delPsi float64, epsilon float64) float64 {
+	i := s.deg2rad(moonEquatorInclination)
+	v := s.deg2rad(omega + delPsi)
+	epsilonRad := s.deg2rad(epsilon)
+	x := math.Sin(i) * math.Sin(v)
+	y := math.Sin(i)*math.Cos(v)*math.Cos(epsilonRad) - math.Cos(i)*math.Sin(epsilonRad)
+	omegaAxis := math.Atan2(x, y)
+	sinP := math.Sqrt(x*x+y*y) * math.Cos(s.deg2rad(alpha)-omegaAxis) / math.Cos(s.deg2rad(b))
+	return s.rad2deg(math.Asin(sinP))
+}
+
+///////////////////////////////////////////////////////////////////////////////////////////
+// Calculate the geocentric optical libration, the position angle of the moon's axis and
+// the sub-solar point for the last calculated instant from the MPA ecliptic coordinates and
+// the spa nutation (Meeus, Astronomical Algorithms, chapter 53, without physical libration)
+///////////////////////////////////////////////////////////////////////////////////////////
+func (s *sampa) CalculateLibration() Libration {
+	var l libration
+	m := &s.mpaData
+	jce := s.spaData.GetJce()
+	delPsi := s.spaData.GetDelPsi()
+	omega := s.moonAscendingNodeLongitude(jce)
+	beta := wrapDegrees(m.beta)
+
+	//mean longitude of the moon, the apparent longitude without nutation
+	l.longitude, l.latitude = s.selenographicPosition(m.lamda-delPsi, beta, omega, m.f)
+	l.positionAngle = s.moonAxisPositionAngle(m.alpha, l.latitude, omega, delPsi, s.spaData.GetEpsilon())
+
+	//heliocentric position of the moon seen from the sun
+	sunLamda := s.spaData.GetLamda()
+	ratio := m.capDelta / (s.spaData.GetR() * astronomicalUnit)
+	lamdaH := sunLamda + 180 + ratio*s.rad2deg(math.Cos(s.deg2rad(beta))*math.Sin(s.deg2rad(sunLamda-m.lamda)))
+	betaH := ratio * beta
+	l.subSolarLongitude, l.subSolarLatitude = s.selenographicPosition(lamdaH-delPsi, betaH, omega, m.f)
+	l.colongitude = s.limitDegrees(90 - l.subSolarLongitude)
+	return &l
+}
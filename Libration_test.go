@@ -0,0 +1,42 @@
+package sampa
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+//Meeus, Astronomical Algorithms, example 53.a: 1992-04-12 0h TD, optical libration l' = -1.206
+//and b' = +4.194 degrees. P = 15.08 and the sub-solar point l0 = 67.89, b0 = 1.46 degrees include
+//the physical libration of a few hundredths of a degree that is not modeled.
+func TestLibration(t *testing.T) {
+	in := referenceInput()
+	in.DeltaT = 59
+	in.setDate(time.Date(1992, 4, 11, 23, 59, 1, 0, time.UTC))
+	s, err := in.sampa()
+	if err != nil {
+		t.Fatal(err)
+	}
+	l := s.CalculateLibration()
+	tests := []struct {
+		name      string
+		got       float64
+		want      float64
+		tolerance float64
+	}{
+		{"longitude", l.GetLongitude(), -1.206, 0.002},
+		{"latitude", l.GetLatitude(), 4.194, 0.002},
+		{"position angle", l.GetPositionAngle(), 15.08, 0.05},
+		{"sub-solar longitude", l.GetSubSolarLongitude(), 67.89, 0.05},
+		{"sub-solar latitude", l.GetSubSolarLatitude(), 1.46, 0.05},
+		{"colongitude", l.GetColongitude(), 90 - l.GetSubSolarLongitude(), 1e-9},
+	}
+	for _, test := range tests {
+		if math.Abs(test.got-test.want) > test.tolerance {
+			t.Errorf("%s %.4f, want %.4f", test.name, test.got, test.want)
+		}
+	}
+	if math.Abs(l.GetLongitude()) > 8 || math.Abs(l.GetLatitude()) > 7 {
+		t.Errorf("libration %.3f, %.3f outside of the optical range", l.GetLongitude(), l.GetLatitude())
+	}
+}
@@ -0,0 +1,175 @@
+package sampa
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+)
+
+const (
+	moonSolidAngle   = 6.4177e-5 //solid angle of the moon at the mean distance of 384400 kilometers [steradians]
+	AngstromExponent = 1.3       //default Angstrom exponent of the aerosol optical depth
+	aerosolReference = 500.0     //wavelength of the Bird aerosol optical depth taua [nanometers]
+)
+
+// RoloBand defines the coefficients of the ROLO disk reflectance model for one band
+// (Kieffer and Stone, The spectral irradiance of the moon, 2005). The coefficients are not
+// part of SAMPA and have to be supplied from the calibration of the instrument.
+type RoloBand struct {
+	Wavelength      float64    //effective wavelength of the band [nanometers]
+	SolarIrradiance float64    //solar spectral irradiance of the band at 1 AU, sets the unit of the results [e.g. W/m^2/nm]
+	A               [4]float64 //polynomial coefficients of the phase angle [per radian^i]
+	B               [3]float64 //odd polynomial coefficients of the selenographic longitude of the sun [per radian^(2j-1)]
+	C               [4]float64 //libration coefficients [per degree]
+	D               [3]float64 //opposition effect coefficients
+	P               [4]float64 //opposition effect parameters [degrees]
+}
+
+// LunarIrradianceOptions defines the aerosol attenuation of the lunar irradiance
+type LunarIrradianceOptions struct {
+	AngstromExponent float64 //wavelength dependence of the aerosol optical depth, AngstromExponent if zero
+}
+
+// LunarIrradiance is the lunar irradiance of one band at the observer
+type LunarIrradiance struct {
+	Wavelength    float64 //effective wavelength of the band [nanometers]
+	Reflectance   float64 //disk equivalent reflectance of the moon [fraction]
+	Irradiance    float64 //irradiance at the top of the atmosphere [unit of the solar irradiance]
+	Rayleigh      float64 //Rayleigh optical depth at the spa pressure
+	Aerosol       float64 //aerosol optical depth from the Bird taua
+	Transmittance float64 //direct transmittance along the line of sight, zero if the moon is below the horizon [fraction]
+	Attenuated    float64 //irradiance at the observer, normal to the line of sight [unit of the solar irradiance]
+}
+
+//columns of a ROLO coefficient CSV file
+var roloColumns = []string{"wavelength", "solar_irradiance", "a0", "a1", "a2", "a3", "b1", "b2", "b3",
+	"c1", "c2", "c3", "c4", "d1", "d2", "d3", "p1", "p2", "p3", "p4"}
+
+// ReadRoloBands reads ROLO band coefficients from CSV with a header row naming the columns
+// wavelength, solar_irradiance, a0 to a3, b1 to b3, c1 to c4, d1 to d3 and p1 to p4 in any order
+func ReadRoloBands(r io.Reader) ([]RoloBand, error) {
+	cr := csv.NewReader(r)
+	cr.TrimLeadingSpace = true
+	header, err := cr.Read()
+	if err != nil {
+		return nil, err
+	}
+	idx := make(map[string]int)
+	for i, name := range header {
+		idx[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	for _, name := range roloColumns {
+		if _, ok := idx[name]; !ok {
+			return nil, fmt.Errorf("missing column %q", name)
+		}
+	}
+
+	var bands []RoloBand
+	for line := 2; ; line++ {
+		record, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		var values [20]float64
+		for i, name := range roloColumns {
+			j := idx[name]
+			if j >= len(record) {
+				return nil, fmt.Errorf("line %d: missing value %q", line, name)
+			}
+			values[i], err = strconv.ParseFloat(strings.TrimSpace(record[j]), 64)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %v", line, err)
+			}
+		}
+		var b RoloBand
+		b.Wavelength, b.SolarIrradiance = values[0], values[1]
+		copy(b.A[:], values[2:6])
+		copy(b.B[:], values[6:9])
+		copy(b.C[:], values[9:13])
+		copy(b.D[:], values[13:16])
+		copy(b.P[:], values[16:20])
+		bands = append(bands, b)
+	}
+	return bands, nil
+}
+
+//disk equivalent reflectance of the ROLO model from the absolute phase angle g [degrees], the selenographic
+//latitude theta and longitude phi of the observer [degrees] and the selenographic longitude of the sun sun [degrees]
+func (b *RoloBand) reflectance(g float64, theta float64, phi float64, sun float64) float64 {
+	gRad := g * math.Pi / 180
+	sunRad := sun * math.Pi / 180
+	lnA := b.A[0] + b.A[1]*gRad + b.A[2]*gRad*gRad + b.A[3]*gRad*gRad*gRad +
+		b.B[0]*sunRad + b.B[1]*math.Pow(sunRad, 3) + b.B[2]*math.Pow(sunRad, 5) +
+		b.C[0]*theta + b.C[1]*phi + b.C[2]*sunRad*theta + b.C[3]*sunRad*phi +
+		b.D[0]*math.Exp(-g/b.P[0]) + b.D[1]*math.Exp(-g/b.P[1]) + b.D[2]*math.Cos((g-b.P[2])/b.P[3])
+	return math.Exp(lnA)
+}
+
+//Rayleigh optical depth at the wavelength [nanometers] and pressure [millibars] (Hansen and Travis 1974)
+func rayleighOpticalDepth(wavelength float64, pressure float64) float64 {
+	l2 := 1e-6 * wavelength * wavelength
+	return 0.008569 / (l2 * l2) * (1 + 0.0113/l2 + 0.00013/(l2*l2)) * pressure / standardPressure
+}
+
+//distance between the observer and the moon center from the geocentric distance capDelta [kilometers] and
+//the topocentric elevation angle (uncorrected) e0 of the moon [degrees] on a spherical earth [kilometers]
+func (s *sampa) topocentricMoonDistance(capDelta float64, e0 float64) float64 {
+	radius := earthRadiusMeters / 1000
+	sinE, cosE := math.Sin(s.deg2rad(e0)), math.Cos(s.deg2rad(e0))
+	return math.Sqrt(capDelta*capDelta-radius*radius*cosE*cosE) - radius*sinE
+}
+
+///////////////////////////////////////////////////////////////////////////////////////////
+// Calculate the lunar irradiance of each ROLO band for the last calculated instant. The disk
+// reflectance follows the phase angle, the geocentric libration and the selenographic
+// longitude of the sun, it is scaled to the sun-moon distance and the distance between the
+// observer and the moon. Rayleigh attenuation scales with the spa pressure, the aerosol
+// optical depth with the Bird taua at 500 nanometers and the Angstrom exponent of opts.
+///////////////////////////////////////////////////////////////////////////////////////////
+func (s *sampa) CalculateLunarIrradiance(bands []RoloBand, opts LunarIrradianceOptions) ([]LunarIrradiance, error) {
+	if len(bands) == 0 {
+		return nil, errors.New("no ROLO bands")
+	}
+	if opts.AngstromExponent == 0 {
+		opts.AngstromExponent = AngstromExponent
+	}
+	m := &s.mpaData
+	g, sunMoon := s.moonPhaseAngle(s.spaData.GetAlphaPrime(), s.spaData.GetDeltaPrime(), m.alphaPrime, m.deltaPrime, s.spaData.GetR(), m.capDelta)
+	l := s.CalculateLibration()
+	distance := s.topocentricMoonDistance(m.capDelta, m.e0)
+	scale := moonSolidAngle / math.Pi * math.Pow(moonMeanDistance/distance, 2) * math.Pow(astronomicalUnit/sunMoon, 2)
+	taua := 0.
+	if s.birdData != nil {
+		taua = s.birdData.GetTaua()
+	}
+	airmass := 0.
+	if m.e > 0 {
+		airmass = kastenYoungAirmass(m.e)
+	}
+
+	irradiances := make([]LunarIrradiance, len(bands))
+	for i := range bands {
+		b := &bands[i]
+		if b.Wavelength <= 0 {
+			return nil, fmt.Errorf("band %d: invalid wavelength %v", i, b.Wavelength)
+		}
+		li := &irradiances[i]
+		li.Wavelength = b.Wavelength
+		li.Reflectance = b.reflectance(g, l.GetLatitude(), l.GetLongitude(), l.GetSubSolarLongitude())
+		li.Irradiance = li.Reflectance * b.SolarIrradiance * scale
+		li.Rayleigh = rayleighOpticalDepth(b.Wavelength, s.spaData.GetPressure())
+		li.Aerosol = taua * math.Pow(b.Wavelength/aerosolReference, -opts.AngstromExponent)
+		if airmass > 0 {
+			li.Transmittance = math.Exp(-(li.Rayleigh + li.Aerosol) * airmass)
+		}
+		li.Attenuated = li.Irradiance * li.Transmittance
+	}
+	return irradiances, nil
+}
@@ -0,0 +1,79 @@
+package sampa
+
+import (
+	"math"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReadRoloBands(t *testing.T) {
+	in := "solar_irradiance, wavelength, a0, a1, a2, a3, b1, b2, b3, c1, c2, c3, c4, d1, d2, d3, p1, p2, p3, p4\n" +
+		"1.86, 550, -2.1, -1.8, 0.5, -0.25, 0.04, 0.01, -0.003, 0.00034115, -0.0013425, 0.00095906, 0.00066229, 0.34, 0.014, -0.016, 4.06054, 12.8802, -30.5858, 16.7498\n"
+	bands, err := ReadRoloBands(strings.NewReader(in))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(bands) != 1 {
+		t.Fatalf("read %d bands, want 1", len(bands))
+	}
+	b := bands[0]
+	if b.Wavelength != 550 || b.SolarIrradiance != 1.86 || b.A[3] != -0.25 || b.B[2] != -0.003 || b.C[3] != 0.00066229 ||
+		b.D[0] != 0.34 || b.P != [4]float64{4.06054, 12.8802, -30.5858, 16.7498} {
+		t.Errorf("band %+v", b)
+	}
+
+	if _, err := ReadRoloBands(strings.NewReader("wavelength,a0\n550,-2\n")); err == nil {
+		t.Error("missing columns accepted")
+	}
+	if _, err := ReadRoloBands(strings.NewReader(strings.Replace(in, "-2.1", "x", 1))); err == nil {
+		t.Error("invalid coefficient accepted")
+	}
+}
+
+//Rayleigh optical depth of the standard atmosphere at 550 nm: 0.0973 (Hansen and Travis 1974);
+//a band of constant reflectance is scaled by the solid angle of the moon and both distances
+func TestLunarIrradiance(t *testing.T) {
+	if tau := rayleighOpticalDepth(550, standardPressure); math.Abs(tau-0.0973) > 5e-4 {
+		t.Errorf("Rayleigh optical depth at 550 nm %.5f, want 0.0973", tau)
+	}
+
+	in := referenceInput()
+	in.DeltaT = 68.2
+	in.setDate(time.Date(2016, 11, 14, 15, 0, 0, 0, time.UTC))
+	s, err := in.sampa()
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := RoloBand{Wavelength: 550, SolarIrradiance: 1.86, P: [4]float64{1, 1, 0, 1}}
+	b.A[0] = math.Log(0.12)
+	li, err := s.CalculateLunarIrradiance([]RoloBand{b}, LunarIrradianceOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := li[0]
+	m := s.GetMpaData()
+	_, sunMoon := s.(*sampa).moonPhaseAngle(s.GetSpaData().GetAlphaPrime(), s.GetSpaData().GetDeltaPrime(), m.GetAlphaPrime(), m.GetDeltaPrime(), s.GetSpaData().GetR(), m.GetCapDelta())
+	distance := s.(*sampa).topocentricMoonDistance(m.GetCapDelta(), m.GetE0())
+	want := 0.12 * 1.86 * moonSolidAngle / math.Pi * math.Pow(moonMeanDistance/distance, 2) * math.Pow(astronomicalUnit/sunMoon, 2)
+	if math.Abs(got.Reflectance-0.12) > 1e-12 || math.Abs(got.Irradiance-want) > 1e-12*want {
+		t.Errorf("reflectance %.6f, irradiance %.6g, want 0.12, %.6g", got.Reflectance, got.Irradiance, want)
+	}
+	//the moon is observed about one earth radius closer than the geocentric distance when high in the sky
+	if d := m.GetCapDelta() - distance; d < 6000 || d > earthRadiusMeters/1000 {
+		t.Errorf("topocentric distance %.0f km, geocentric %.0f km", distance, m.GetCapDelta())
+	}
+	wantAerosol := in.Taua * math.Pow(550/aerosolReference, -AngstromExponent)
+	wantTransmittance := math.Exp(-(got.Rayleigh + wantAerosol) * kastenYoungAirmass(m.GetE()))
+	if math.Abs(got.Aerosol-wantAerosol) > 1e-12 || math.Abs(got.Transmittance-wantTransmittance) > 1e-12 ||
+		math.Abs(got.Attenuated-got.Irradiance*got.Transmittance) > 1e-18 {
+		t.Errorf("aerosol %.5f, transmittance %.5f, want %.5f, %.5f", got.Aerosol, got.Transmittance, wantAerosol, wantTransmittance)
+	}
+
+	if _, err := s.CalculateLunarIrradiance(nil, LunarIrradianceOptions{}); err == nil {
+		t.Error("no bands accepted")
+	}
+	if _, err := s.CalculateLunarIrradiance([]RoloBand{{}}, LunarIrradianceOptions{}); err == nil {
+		t.Error("zero wavelength accepted")
+	}
+}
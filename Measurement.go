@@ -0,0 +1,277 @@
+package sampa
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/maltegrosse/go-bird"
+)
+
+const (
+	fitTolerance   = 1e-6 //tolerance of fitted parameters
+	fitMaxZenith   = 85.0 //samples with a larger sun zenith angle are not used for fitting [degrees]
+	fitTauaMaximum = 1.5  //upper limit of the fitted broadband aerosol optical depth
+)
+
+// Measurement is a timestamped irradiance sample, NaN marks a missing value
+type Measurement struct {
+	Time time.Time
+	Ghi  float64 //measured global horizontal irradiance [W/m^2]
+	Dni  float64 //measured direct normal irradiance [W/m^2]
+}
+
+// MeasurementOptions defines the layout of a measurement CSV file with a header row
+type MeasurementOptions struct {
+	TimeColumn string         //header of the timestamp column, "time" if empty
+	GhiColumn  string         //header of the global horizontal irradiance column, "ghi" if empty
+	DniColumn  string         //header of the direct normal irradiance column, "dni" if empty
+	TimeFormat string         //layout of timestamps, time.RFC3339 if empty
+	Location   *time.Location //location of timestamps without zone, UTC if nil
+	Comma      rune           //field delimiter, ',' if zero
+}
+
+// ReadMeasurements reads timestamped irradiance samples from CSV, a missing column or an empty field is read as NaN
+func ReadMeasurements(r io.Reader, opts MeasurementOptions) ([]Measurement, error) {
+	if opts.TimeColumn == "" {
+		opts.TimeColumn = "time"
+	}
+	if opts.GhiColumn == "" {
+		opts.GhiColumn = "ghi"
+	}
+	if opts.DniColumn == "" {
+		opts.DniColumn = "dni"
+	}
+	if opts.TimeFormat == "" {
+		opts.TimeFormat = time.RFC3339
+	}
+	if opts.Location == nil {
+		opts.Location = time.UTC
+	}
+
+	cr := csv.NewReader(r)
+	if opts.Comma != 0 {
+		cr.Comma = opts.Comma
+	}
+	cr.TrimLeadingSpace = true
+	header, err := cr.Read()
+	if err != nil {
+		return nil, err
+	}
+	timeIdx, ghiIdx, dniIdx := -1, -1, -1
+	for i, name := range header {
+		switch strings.TrimSpace(name) {
+		case opts.TimeColumn:
+			timeIdx = i
+		case opts.GhiColumn:
+			ghiIdx = i
+		case opts.DniColumn:
+			dniIdx = i
+		}
+	}
+	if timeIdx < 0 {
+		return nil, fmt.Errorf("missing time column %q", opts.TimeColumn)
+	}
+
+	field := func(record []string, idx int) (float64, error) {
+		if idx < 0 || idx >= len(record) || strings.TrimSpace(record[idx]) == "" {
+			return math.NaN(), nil
+		}
+		return strconv.ParseFloat(strings.TrimSpace(record[idx]), 64)
+	}
+
+	var ms []Measurement
+	for line := 2; ; line++ {
+		record, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		var m Measurement
+		m.Time, err = time.ParseInLocation(opts.TimeFormat, strings.TrimSpace(record[timeIdx]), opts.Location)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", line, err)
+		}
+		m.Ghi, err = field(record, ghiIdx)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", line, err)
+		}
+		m.Dni, err = field(record, dniIdx)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", line, err)
+		}
+		ms = append(ms, m)
+	}
+	return ms, nil
+}
+
+// Residual compares a measurement with the SAMPA estimates for its timestamp
+type Residual struct {
+	Measurement
+	SunZenith     float64 //topocentric sun zenith angle [degrees]
+	ASulPct       float64 //percent area of SUL [percent]
+	GhiSul        float64 //estimated global horizontal irradiance from the SUL [W/m^2]
+	DniSul        float64 //estimated direct normal irradiance from the SUL [W/m^2]
+	GhiResidual   float64 //measured minus estimated global horizontal irradiance [W/m^2]
+	DniResidual   float64 //measured minus estimated direct normal irradiance [W/m^2]
+	ClearSkyIndex float64 //measured over estimated global horizontal irradiance, NaN without estimate
+}
+
+// Comparison summarizes measurements against SAMPA estimates
+type Comparison struct {
+	Residuals []Residual
+	GhiBias   float64   //mean global horizontal residual, NaN without measured values [W/m^2]
+	GhiRmse   float64   //root mean square global horizontal residual, NaN without measured values [W/m^2]
+	DniBias   float64   //mean direct normal residual, NaN without measured values [W/m^2]
+	DniRmse   float64   //root mean square direct normal residual, NaN without measured values [W/m^2]
+	Taua      float64   //broadband aerosol optical depth fitted to the pre-eclipse samples
+	Bird      bird.Bird //Bird model with the fitted taua at the last pre-eclipse sample, NaN taua and nil without pre-eclipse samples
+}
+
+//sun geometry and modeled values of one sample for fitting
+type fitSample struct {
+	t        time.Time //time of the measurement
+	zenith   float64   //topocentric sun zenith angle [degrees]
+	r        float64   //earth radius vector [AU]
+	pressure float64   //local pressure [millibars]
+	dniMod   float64   //fraction of the unshaded sun disk
+	ghi      float64   //measured global horizontal irradiance [W/m^2]
+	dni      float64   //measured direct normal irradiance [W/m^2]
+}
+
+//sum of squared residuals of measured and Bird modeled irradiances with the given parameters
+func fitCost(samples []fitSample, ozone float64, water float64, taua float64, ba float64, albedo float64) float64 {
+	cost := 0.
+	for _, fs := range samples {
+		b, err := bird.NewBird(fs.zenith, fs.r, fs.pressure, ozone, water, taua, ba, albedo, fs.dniMod)
+		if err != nil {
+			return math.Inf(1)
+		}
+		if !math.IsNaN(fs.ghi) {
+			d := fs.ghi - b.GetGlobalHorizMod()
+			cost += d * d
+		}
+		if !math.IsNaN(fs.dni) {
+			d := fs.dni - b.GetDirectNormalMod()
+			cost += d * d
+		}
+	}
+	return cost
+}
+
+//recalculate s for each measurement and collect the residuals and the samples for fitting,
+//the date of the spa data and the outputs of s are restored afterwards
+func residuals(ctx context.Context, s Sampa, ms []Measurement, progress Progress) (rs []Residual, samples []fitSample, err error) {
+	sp := s.GetSpaData()
+	defer restoreDate(s, &err)()
+
+	rs = make([]Residual, 0, len(ms))
+	samples = make([]fitSample, 0, len(ms))
+	pc := newProgressCounter(progress, len(ms))
+	for _, m := range ms {
+		err = ctx.Err()
+		if err != nil {
+			return nil, nil, err
+		}
+		sp.SetDate(m.Time)
+		err = s.Calculate()
+		if err != nil {
+			return nil, nil, err
+		}
+		r := Residual{
+			Measurement:   m,
+			SunZenith:     s.GetSunZenith(),
+			ASulPct:       s.GetASulPct(),
+			GhiSul:        s.GetGhiSul(),
+			DniSul:        s.GetDniSul(),
+			GhiResidual:   m.Ghi - s.GetGhiSul(),
+			DniResidual:   m.Dni - s.GetDniSul(),
+			ClearSkyIndex: math.NaN(),
+		}
+		if r.GhiSul > 0 {
+			r.ClearSkyIndex = m.Ghi / r.GhiSul
+		}
+		rs = append(rs, r)
+		samples = append(samples, fitSample{t: m.Time, zenith: s.GetSunZenith(), r: sp.GetR(), pressure: sp.GetPressure(),
+			dniMod: s.GetASulPct() / 100, ghi: m.Ghi, dni: m.Dni})
+		pc.add(1)
+	}
+	return rs, samples, nil
+}
+
+//samples before the first eclipsed sample in time with the sun high enough and a measured value, usable
+//for fitting, in time order
+func preEclipseSamples(samples []fitSample) []fitSample {
+	sorted := append([]fitSample(nil), samples...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].t.Before(sorted[j].t) })
+	var clear []fitSample
+	for _, fs := range sorted {
+		if fs.dniMod < 1 {
+			break
+		}
+		if fs.zenith >= fitMaxZenith || (math.IsNaN(fs.ghi) && math.IsNaN(fs.dni)) {
+			continue
+		}
+		clear = append(clear, fs)
+	}
+	return clear
+}
+
+///////////////////////////////////////////////////////////////////////////////////////////
+// Compare measured irradiances with the SAMPA estimates of s for the same timestamps and
+// fit the broadband aerosol optical depth (taua) of the Bird model to the samples before the
+// eclipse, assuming clear conditions before the eclipse. Progress counts the measurements.
+///////////////////////////////////////////////////////////////////////////////////////////
+func CompareMeasurements(ctx context.Context, s Sampa, ms []Measurement, progress Progress) (Comparison, error) {
+	var c Comparison
+	if s.GetFunction() != SampaAll {
+		return c, errors.New("comparison requires function SampaAll")
+	}
+	rs, all, err := residuals(ctx, s, ms, progress)
+	if err != nil {
+		return c, err
+	}
+	c.Residuals = rs
+
+	var nGhi, nDni float64
+	for _, r := range rs {
+		if !math.IsNaN(r.GhiResidual) {
+			c.GhiBias += r.GhiResidual
+			c.GhiRmse += r.GhiResidual * r.GhiResidual
+			nGhi++
+		}
+		if !math.IsNaN(r.DniResidual) {
+			c.DniBias += r.DniResidual
+			c.DniRmse += r.DniResidual * r.DniResidual
+			nDni++
+		}
+	}
+	c.GhiBias, c.GhiRmse = c.GhiBias/nGhi, math.Sqrt(c.GhiRmse/nGhi)
+	c.DniBias, c.DniRmse = c.DniBias/nDni, math.Sqrt(c.DniRmse/nDni)
+
+	c.Taua = math.NaN()
+	samples := preEclipseSamples(all)
+	if len(samples) == 0 {
+		return c, nil
+	}
+	b := s.GetBirdData()
+	c.Taua, err = goldenSection(func(taua float64) (float64, error) {
+		return fitCost(samples, b.GetOzone(), b.GetWater(), taua, b.GetBa(), b.GetAlbedo()), nil
+	}, 0, fitTauaMaximum, fitTolerance)
+	if err != nil {
+		return c, err
+	}
+
+	last := samples[len(samples)-1]
+	c.Bird, err = bird.NewBird(last.zenith, last.r, last.pressure, b.GetOzone(), b.GetWater(), c.Taua, b.GetBa(), b.GetAlbedo(), last.dniMod)
+	return c, err
+}
@@ -0,0 +1,144 @@
+package sampa
+
+import (
+	"context"
+	"math"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+//total eclipse of 2017-08-21 in southern Illinois, first contact near 16:52 UTC
+func illinoisEclipseInput() goldenInput {
+	in := referenceInput()
+	in.setDate(time.Date(2017, 8, 21, 18, 25, 0, 0, time.UTC))
+	in.Latitude = 37.58
+	in.Longitude = -89.12
+	in.DeltaT = 68.9
+	in.Elevation = 250
+	return in
+}
+
+//measurements every ten minutes modeled with the given taua, written as CSV with a header row
+func syntheticMeasurements(t *testing.T, taua float64, start time.Time, end time.Time) string {
+	in := illinoisEclipseInput()
+	in.Taua = taua
+	s, err := in.sampa()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var sb strings.Builder
+	sb.WriteString("time;ghi;dni\n")
+	err = Walk(context.Background(), s, start, end, 10*time.Minute, nil, func(s Sampa) error {
+		sb.WriteString(s.GetSpaData().GetDate().Format(time.RFC3339) + ";" +
+			strconv.FormatFloat(s.GetGhiSul(), 'g', -1, 64) + ";" +
+			strconv.FormatFloat(s.GetDniSul(), 'g', -1, 64) + "\n")
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return sb.String()
+}
+
+func TestReadMeasurements(t *testing.T) {
+	in := "stamp,dni,ghi\n2017-08-21 15:00,800.5,\n2017-08-21 15:10, ,612\n"
+	ms, err := ReadMeasurements(strings.NewReader(in), MeasurementOptions{TimeColumn: "stamp", TimeFormat: "2006-01-02 15:04"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ms) != 2 {
+		t.Fatalf("read %d measurements, want 2", len(ms))
+	}
+	if want := time.Date(2017, 8, 21, 15, 10, 0, 0, time.UTC); !ms[1].Time.Equal(want) {
+		t.Errorf("time %v, want %v", ms[1].Time, want)
+	}
+	if ms[0].Dni != 800.5 || !math.IsNaN(ms[0].Ghi) || !math.IsNaN(ms[1].Dni) || ms[1].Ghi != 612 {
+		t.Errorf("measurements %+v, empty fields must be NaN", ms)
+	}
+
+	ms, err = ReadMeasurements(strings.NewReader("time\n2017-08-21T15:00:00Z\n"), MeasurementOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ms) != 1 || !math.IsNaN(ms[0].Ghi) || !math.IsNaN(ms[0].Dni) {
+		t.Errorf("measurements %+v, missing columns must be NaN", ms)
+	}
+
+	if _, err := ReadMeasurements(strings.NewReader("ghi,dni\n1,2\n"), MeasurementOptions{}); err == nil {
+		t.Error("missing time column accepted")
+	}
+	if _, err := ReadMeasurements(strings.NewReader("time,ghi\n2017-08-21T15:00:00Z,bright\n"), MeasurementOptions{}); err == nil {
+		t.Error("invalid irradiance accepted")
+	}
+}
+
+//the aerosol optical depth of synthetic measurements is recovered from the samples before first contact
+func TestCompareMeasurements(t *testing.T) {
+	start := time.Date(2017, 8, 21, 14, 0, 0, 0, time.UTC)
+	end := time.Date(2017, 8, 21, 19, 0, 0, 0, time.UTC)
+	ms, err := ReadMeasurements(strings.NewReader(syntheticMeasurements(t, 0.2, start, end)), MeasurementOptions{Comma: ';'})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := illinoisEclipseInput().sampa()
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := CompareMeasurements(context.Background(), s, ms, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(c.Residuals) != len(ms) {
+		t.Fatalf("%d residuals, want %d", len(c.Residuals), len(ms))
+	}
+	if math.Abs(c.Taua-0.2) > 1e-4 {
+		t.Errorf("fitted taua %.6f, want 0.2", c.Taua)
+	}
+	if c.Bird == nil || c.Bird.GetTaua() != c.Taua {
+		t.Fatalf("Bird model without the fitted taua")
+	}
+	//the Bird model is evaluated at the last sample before first contact near 16:52 UTC
+	last := -1
+	for i, r := range c.Residuals {
+		if r.SunZenith == c.Bird.GetZenith() {
+			last = i
+		}
+	}
+	if last < 0 || c.Bird.GetDniMod() != 1 || c.Residuals[last+1].ASulPct >= 100 {
+		t.Errorf("Bird model at sun zenith %.4f, dniMod %g, not at the last pre-eclipse sample", c.Bird.GetZenith(), c.Bird.GetDniMod())
+	}
+	//a more turbid atmosphere than the model: less direct and global irradiance
+	if c.DniBias >= 0 || c.GhiBias >= 0 || c.DniRmse < -c.DniBias {
+		t.Errorf("bias GHI %.3f, DNI %.3f, RMSE DNI %.3f", c.GhiBias, c.DniBias, c.DniRmse)
+	}
+	if !s.GetSpaData().GetDate().Equal(illinoisEclipseInput().date()) {
+		t.Errorf("spa date %v not restored", s.GetSpaData().GetDate())
+	}
+
+	//measurements out of time order are fitted to the same samples before the eclipse
+	reversed := make([]Measurement, len(ms))
+	for i, m := range ms {
+		reversed[len(ms)-1-i] = m
+	}
+	r, err := CompareMeasurements(context.Background(), s, reversed, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Taua != c.Taua || r.Bird.GetZenith() != c.Bird.GetZenith() {
+		t.Errorf("reversed measurements: taua %.6f at zenith %.4f, want %.6f at %.4f", r.Taua, r.Bird.GetZenith(), c.Taua, c.Bird.GetZenith())
+	}
+
+	//no irradiance samples: biases and errors are NaN and no taua is fitted
+	for i := range ms {
+		ms[i].Ghi, ms[i].Dni = math.NaN(), math.NaN()
+	}
+	c, err = CompareMeasurements(context.Background(), s, ms, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !math.IsNaN(c.GhiBias) || !math.IsNaN(c.DniRmse) || !math.IsNaN(c.Taua) || c.Bird != nil {
+		t.Errorf("comparison without samples: %+v", c)
+	}
+}
@@ -0,0 +1,128 @@
+package sampa
+
+import (
+	"math"
+)
+
+const (
+	moonMeanDistance     = 384400.0 //mean distance between the centers of earth and moon [kilometers]
+	zeroMagnitudeLux     = 2.54e-6  //illuminance of a star of visual magnitude zero outside the atmosphere [lux]
+	rayleighExtinction   = 0.1066   //Rayleigh extinction in the V band at standard pressure [magnitudes/airmass]
+	ozoneExtinction      = 0.031    //ozone extinction in the V band for 0.3 atm-cm of ozone [magnitudes/airmass]
+	aerosolExtinction    = 1.086    //extinction per unit aerosol optical depth (2.5 log10 e) [magnitudes/airmass]
+	standardPressure     = 1013.25  //standard atmospheric pressure [millibars]
+	referenceOzoneColumn = 0.3      //ozone column of the ozone extinction coefficient [atm-cm]
+)
+
+// Moonlight interface defines the apparent brightness of the moon and its illuminance at the observer
+type Moonlight interface {
+	//phase angle, angle between sun and earth seen from the moon, valid range: 0 to 180 [degrees]
+	GetPhaseAngle() float64
+	//illuminated fraction of the moon disk, valid range: 0 to 1 [fraction]
+	GetIlluminatedFraction() float64
+	//apparent visual magnitude outside the atmosphere [magnitudes]
+	GetMagnitude() float64
+	//relative optical airmass along the line of sight, zero if the moon is below the horizon
+	GetAirmass() float64
+	//extinction coefficient in the V band from pressure and the Bird ozone and aerosol inputs [magnitudes/airmass]
+	GetExtinction() float64
+	//apparent visual magnitude at the observer, including the atmospheric extinction [magnitudes]
+	GetObservedMagnitude() float64
+	//moonlight illuminance on a horizontal surface, zero if the moon is below the horizon [lux]
+	GetIlluminance() float64
+}
+
+type moonlight struct {
+	phaseAngle          float64 //phase angle of the moon [degrees]
+	illuminatedFraction float64 //illuminated fraction of the moon disk [fraction]
+	magnitude           float64 //apparent visual magnitude outside the atmosphere [magnitudes]
+	airmass             float64 //relative optical airmass
+	extinction          float64 //extinction coefficient in the V band [magnitudes/airmass]
+	observedMagnitude   float64 //apparent visual magnitude at the observer [magnitudes]
+	illuminance         float64 //horizontal moonlight illuminance [lux]
+}
+
+func (m *moonlight) GetPhaseAngle() float64 {
+	return m.phaseAngle
+}
+
+func (m *moonlight) GetIlluminatedFraction() float64 {
+	return m.illuminatedFraction
+}
+
+func (m *moonlight) GetMagnitude() float64 {
+	return m.magnitude
+}
+
+func (m *moonlight) GetAirmass() float64 {
+	return m.airmass
+}
+
+func (m *moonlight) GetExtinction() float64 {
+	return m.extinction
+}
+
+func (m *moonlight) GetObservedMagnitude() float64 {
+	return m.observedMagnitude
+}
+
+func (m *moonlight) GetIlluminance() float64 {
+	return m.illuminance
+}
+
+//phase angle of the moon [degrees] and distance between the centers of sun and moon [kilometers] from the
+//topocentric positions of sun and moon, the earth-sun distance r [AU] and the earth-moon distance capDelta
+//[kilometers] (Meeus, Astronomical Algorithms, 48.2 and 48.3)
+func (s *sampa) moonPhaseAngle(alphaSun float64, deltaSun float64, alphaMoon float64, deltaMoon float64, r float64, capDelta float64) (float64, float64) {
+	psi := s.deg2rad(s.angularSeparation(alphaSun, deltaSun, alphaMoon, deltaMoon))
+	rKm := r * astronomicalUnit
+	x, y := capDelta-rKm*math.Cos(psi), rKm*math.Sin(psi)
+	return s.rad2deg(math.Atan2(y, x)), math.Hypot(x, y)
+}
+
+//angular distance between two positions given by right ascension and declination [degrees]
+func (s *sampa) angularSeparation(alpha1 float64, delta1 float64, alpha2 float64, delta2 float64) float64 {
+	delta1Rad := s.deg2rad(delta1)
+	delta2Rad := s.deg2rad(delta2)
+	cosPsi := math.Sin(delta1Rad)*math.Sin(delta2Rad) +
+		math.Cos(delta1Rad)*math.Cos(delta2Rad)*math.Cos(s.deg2rad(alpha1-alpha2))
+	return s.rad2deg(math.Acos(math.Max(-1, math.Min(1, cosPsi))))
+}
+
+//apparent visual magnitude of the moon outside the atmosphere from the phase angle i [degrees], the earth-sun
+//distance r [AU] and the earth-moon distance capDelta [kilometers] (Allen, Krisciunas and Schaefer 1991)
+func moonMagnitude(i float64, r float64, capDelta float64) float64 {
+	return -12.73 + 0.026*math.Abs(i) + 4e-9*math.Pow(i, 4) + 5*math.Log10(r*capDelta/moonMeanDistance)
+}
+
+//relative optical airmass at the corrected elevation angle e (Kasten and Young 1989)
+func kastenYoungAirmass(e float64) float64 {
+	return 1 / (math.Sin(e*math.Pi/180) + 0.50572*math.Pow(e+6.07995, -1.6364))
+}
+
+///////////////////////////////////////////////////////////////////////////////////////////
+// Calculate the apparent magnitude of the moon and the moonlight illuminance on a horizontal
+// surface for the last calculated instant. The Rayleigh extinction scales with the spa
+// pressure, ozone and aerosol extinction follow the Bird ozone and taua inputs if a Bird
+// structure is set; the temperature enters through the refraction corrected moon elevation.
+///////////////////////////////////////////////////////////////////////////////////////////
+func (s *sampa) CalculateMoonlight() Moonlight {
+	var l moonlight
+	m := &s.mpaData
+	r := s.spaData.GetR()
+	l.phaseAngle, _ = s.moonPhaseAngle(s.spaData.GetAlphaPrime(), s.spaData.GetDeltaPrime(), m.alphaPrime, m.deltaPrime, r, m.capDelta)
+	l.illuminatedFraction = (1 + math.Cos(s.deg2rad(l.phaseAngle))) / 2
+	l.magnitude = moonMagnitude(l.phaseAngle, r, m.capDelta)
+
+	l.extinction = rayleighExtinction * s.spaData.GetPressure() / standardPressure
+	if s.birdData != nil {
+		l.extinction += ozoneExtinction*s.birdData.GetOzone()/referenceOzoneColumn + aerosolExtinction*s.birdData.GetTaua()
+	}
+	l.observedMagnitude = l.magnitude
+	if m.e > 0 {
+		l.airmass = kastenYoungAirmass(m.e)
+		l.observedMagnitude += l.extinction * l.airmass
+		l.illuminance = zeroMagnitudeLux * math.Pow(10, -0.4*l.observedMagnitude) * math.Sin(s.deg2rad(m.e))
+	}
+	return &l
+}
@@ -0,0 +1,52 @@
+package sampa
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+//the full moon illuminates a horizontal surface with 0.2 to 0.3 lux when high in a clear sky
+//(Krisciunas and Schaefer 1991), a quarter moon with about a tenth of that at most
+func TestMoonlight(t *testing.T) {
+	in := referenceInput()
+	in.DeltaT = 68.2
+	in.setDate(time.Date(2016, 11, 14, 15, 0, 0, 0, time.UTC))
+	s, err := in.sampa()
+	if err != nil {
+		t.Fatal(err)
+	}
+	full := s.CalculateMoonlight()
+	if s.GetMpaData().GetE() < 60 {
+		t.Fatalf("moon elevation %.2f, want a high full moon", s.GetMpaData().GetE())
+	}
+	if lux := full.GetIlluminance(); lux < 0.2 || lux > 0.3 {
+		t.Errorf("full moon illuminance %.3f lux, want 0.2 to 0.3", lux)
+	}
+	if full.GetIlluminatedFraction() < 0.99 || math.Abs(full.GetMagnitude()+12.7) > 0.2 {
+		t.Errorf("full moon fraction %.4f, magnitude %.2f", full.GetIlluminatedFraction(), full.GetMagnitude())
+	}
+	if want := full.GetMagnitude() + full.GetExtinction()*full.GetAirmass(); math.Abs(full.GetObservedMagnitude()-want) > 1e-12 {
+		t.Errorf("observed magnitude %.4f, want %.4f", full.GetObservedMagnitude(), want)
+	}
+
+	in.setDate(time.Date(2016, 11, 21, 18, 0, 0, 0, time.UTC))
+	if s, err = in.sampa(); err != nil {
+		t.Fatal(err)
+	}
+	quarter := s.CalculateMoonlight()
+	if f := quarter.GetIlluminatedFraction(); math.Abs(f-0.5) > 0.1 {
+		t.Errorf("last quarter fraction %.3f", f)
+	}
+	if lux := quarter.GetIlluminance(); lux <= 0 || lux > 0.03 {
+		t.Errorf("last quarter illuminance %.4f lux", lux)
+	}
+
+	in.setDate(time.Date(2016, 11, 14, 5, 0, 0, 0, time.UTC))
+	if s, err = in.sampa(); err != nil {
+		t.Fatal(err)
+	}
+	if below := s.CalculateMoonlight(); below.GetIlluminance() != 0 || below.GetAirmass() != 0 {
+		t.Errorf("moon below the horizon: %.4f lux at airmass %.2f", below.GetIlluminance(), below.GetAirmass())
+	}
+}
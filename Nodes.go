@@ -0,0 +1,231 @@
+package sampa
+
+import (
+	"context"
+	"math"
+	"sort"
+	"time"
+)
+
+// NodeKind defines a crossing of the ecliptic by the moon
+type NodeKind uint32
+
+// enumeration for the nodes of the lunar orbit
+const (
+	AscendingNode  NodeKind = 0 //moon latitude changes from south to north
+	DescendingNode NodeKind = 1 //moon latitude changes from north to south
+)
+
+// DeclinationKind defines a monthly extreme of the moon declination
+type DeclinationKind uint32
+
+// enumeration for the monthly extremes of the moon declination
+const (
+	NorthernDeclination DeclinationKind = 0 //maximum declination
+	SouthernDeclination DeclinationKind = 1 //minimum declination
+)
+
+// StandstillKind defines an extreme of the 18.6 year cycle of the monthly moon declination range
+type StandstillKind uint32
+
+// enumeration for the lunar standstills
+const (
+	MajorStandstill StandstillKind = 0 //largest declination range, ascending node at the vernal equinox
+	MinorStandstill StandstillKind = 1 //smallest declination range, ascending node at the autumnal equinox
+)
+
+const (
+	standstillStep   = 30 * 24 * time.Hour  //sampling step of the ascending node longitude
+	standstillWindow = 365 * 24 * time.Hour //declination extremes within this window around the node epoch
+)
+
+// NodePassage is a crossing of the ecliptic by the moon
+type NodePassage struct {
+	Time      time.Time
+	Kind      NodeKind
+	Longitude float64 //apparent geocentric moon longitude [degrees]
+}
+
+// DeclinationExtreme is a monthly extreme of the geocentric moon declination
+type DeclinationExtreme struct {
+	Time        time.Time
+	Kind        DeclinationKind
+	Declination float64 //geocentric moon declination [degrees]
+}
+
+// Standstill is a major or minor lunar standstill
+type Standstill struct {
+	Time  time.Time //mean ascending node at the vernal (major) or autumnal (minor) equinox
+	Kind  StandstillKind
+	North DeclinationExtreme //northern extreme within a year of Time, largest for major and smallest for minor standstills
+	South DeclinationExtreme //southern extreme within a year of Time, largest for major and smallest for minor standstills
+}
+
+//longitude of the mean ascending node of the lunar orbit (Meeus, Astronomical Algorithms, 47.7) [degrees]
+func (s *sampa) moonAscendingNodeLongitude(jce float64) float64 {
+	return s.limitDegrees(s.meanAscendingNodeLongitude(jce))
+}
+
+//longitude of the mean ascending node, not limited to one turn [degrees]
+func (s *sampa) meanAscendingNodeLongitude(jce float64) float64 {
+	return 125.0445479 - 1934.1362891*jce + 0.0020754*jce*jce +
+		jce*jce*jce/467441 - jce*jce*jce*jce/60616000
+}
+
+func (s *sampa) moonLatitudeAt(t time.Time) (float64, error) {
+	g, err := s.geocentricAt(t)
+	return g.moonBeta, err
+}
+
+func (s *sampa) moonDeclinationAt(t time.Time) (float64, error) {
+	g, err := s.geocentricAt(t)
+	return g.moonDelta, err
+}
+
+///////////////////////////////////////////////////////////////////////////////////////////
+// Find the ascending and descending node passages of the moon from start to end: the moon
+// latitude is sampled every six hours and each sign change is refined to one second. The date
+// of the spa data and the SAMPA outputs are restored afterwards. Progress counts the samples.
+///////////////////////////////////////////////////////////////////////////////////////////
+func (s *sampa) CalculateNodePassages(ctx context.Context, start time.Time, end time.Time, progress Progress) (passages []NodePassage, err error) {
+	defer restoreDate(s, &err)()
+	start = start.Truncate(time.Second)
+	last := end.Add(searchStep)
+	pc := newProgressCounter(progress, stepCount(start, last, searchStep))
+	roots, err := scanRoots(ctx, start, last, searchStep, pc, 90, s.moonLatitudeAt)
+	if err != nil {
+		return nil, err
+	}
+	for _, r := range roots {
+		if r.t.After(end) {
+			continue
+		}
+		p := NodePassage{Time: r.t, Kind: DescendingNode}
+		if r.rising {
+			p.Kind = AscendingNode
+		}
+		g, err := s.geocentricAt(p.Time)
+		if err != nil {
+			return nil, err
+		}
+		p.Longitude = s.limitDegrees(g.moonLamda)
+		passages = append(passages, p)
+	}
+	return passages, nil
+}
+
+///////////////////////////////////////////////////////////////////////////////////////////
+// Find the monthly northern and southern extremes of the geocentric moon declination from
+// start to end: the declination is sampled every six hours and each local extremum is
+// refined to one second. The date of the spa data and the SAMPA outputs are restored
+// afterwards. Progress counts the samples.
+///////////////////////////////////////////////////////////////////////////////////////////
+func (s *sampa) CalculateDeclinationExtremes(ctx context.Context, start time.Time, end time.Time, progress Progress) (extremes []DeclinationExtreme, err error) {
+	defer restoreDate(s, &err)()
+	start = start.Truncate(time.Second)
+	pc := newProgressCounter(progress, declinationSamples(start, end))
+	return s.declinationExtremes(ctx, start, end, pc)
+}
+
+//number of samples of the declination extremes from start to end
+func declinationSamples(start time.Time, end time.Time) int {
+	return stepCount(start.Add(-searchStep), end.Add(searchStep), searchStep)
+}
+
+//declination extremes from start (whole seconds) to end, pc counts the samples
+func (s *sampa) declinationExtremes(ctx context.Context, start time.Time, end time.Time, pc *progressCounter) ([]DeclinationExtreme, error) {
+	extrema, err := scanExtrema(ctx, start.Add(-searchStep), end.Add(searchStep), searchStep, pc, s.moonDeclinationAt)
+	if err != nil {
+		return nil, err
+	}
+	var extremes []DeclinationExtreme
+	for _, e := range extrema {
+		d := DeclinationExtreme{Time: e.t, Kind: SouthernDeclination, Declination: e.v}
+		if e.maximum {
+			d.Kind = NorthernDeclination
+		}
+		if !d.Time.Before(start) && !d.Time.After(end) {
+			extremes = append(extremes, d)
+		}
+	}
+	return extremes, nil
+}
+
+//number of standstill epochs of a kind from start to end, the mean node regresses steadily
+func (s *sampa) standstillEpochs(start time.Time, end time.Time, kind StandstillKind) (int, error) {
+	gStart, err := s.geocentricAt(start)
+	if err != nil {
+		return 0, err
+	}
+	gEnd, err := s.geocentricAt(end)
+	if err != nil {
+		return 0, err
+	}
+	offset := 180 * float64(kind)
+	turns := func(jce float64) float64 {
+		return math.Floor((s.meanAscendingNodeLongitude(jce) - offset) / 360)
+	}
+	return int(turns(gStart.jce) - turns(gEnd.jce)), nil
+}
+
+///////////////////////////////////////////////////////////////////////////////////////////
+// Find the major and minor lunar standstills from start to end: the epochs of the mean
+// ascending node at the equinoxes are refined to one second, the monthly declination
+// extremes within a year around each epoch give the extreme declinations of the standstill.
+// The date of the spa data and the SAMPA outputs are restored afterwards. Progress counts the
+// samples of the node longitude and of the declination around each epoch.
+///////////////////////////////////////////////////////////////////////////////////////////
+func (s *sampa) CalculateStandstills(ctx context.Context, start time.Time, end time.Time, progress Progress) (standstills []Standstill, err error) {
+	defer restoreDate(s, &err)()
+	start = start.Truncate(time.Second)
+	last := end.Add(standstillStep)
+
+	//the number of epochs is known in advance, so one count spans all scans
+	total := 0
+	for kind := MajorStandstill; kind <= MinorStandstill; kind++ {
+		epochs, err := s.standstillEpochs(start, end, kind)
+		if err != nil {
+			return nil, err
+		}
+		total += stepCount(start, last, standstillStep) + epochs*declinationSamples(start, start.Add(2*standstillWindow))
+	}
+	pc := newProgressCounter(progress, total)
+
+	for kind := MajorStandstill; kind <= MinorStandstill; kind++ {
+		offset := 180 * float64(kind)
+		roots, err := scanRoots(ctx, start, last, standstillStep, pc, 90, func(t time.Time) (float64, error) {
+			g, err := s.geocentricAt(t)
+			return wrapDegrees(s.moonAscendingNodeLongitude(g.jce) - offset), err
+		})
+		if err != nil {
+			return nil, err
+		}
+		for _, r := range roots {
+			//the node regresses, the relative longitude falls through zero at the epoch
+			if r.rising || r.t.After(end) {
+				continue
+			}
+			st := Standstill{Time: r.t, Kind: kind}
+			extremes, err := s.declinationExtremes(ctx, r.t.Add(-standstillWindow), r.t.Add(standstillWindow), pc)
+			if err != nil {
+				return nil, err
+			}
+			north, south := math.Inf(-1), math.Inf(-1)
+			for _, d := range extremes {
+				v := math.Abs(d.Declination)
+				if kind == MinorStandstill {
+					v = -v
+				}
+				if d.Kind == NorthernDeclination && v > north {
+					st.North, north = d, v
+				}
+				if d.Kind == SouthernDeclination && v > south {
+					st.South, south = d, v
+				}
+			}
+			standstills = append(standstills, st)
+		}
+	}
+	sort.Slice(standstills, func(i, j int) bool { return standstills[i].Time.Before(standstills[j].Time) })
+	return standstills, nil
+}
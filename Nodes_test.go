@@ -0,0 +1,70 @@
+package sampa
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+)
+
+//ascending node passage of May 1987 (Meeus, Astronomical Algorithms, example 51.a):
+//1987-05-23 06:25.9 TD, 06:25 UT with delta T of 55.3 seconds
+func TestNodePassages(t *testing.T) {
+	in := referenceInput()
+	in.DeltaT = 55.3
+	s, err := in.sampa()
+	if err != nil {
+		t.Fatal(err)
+	}
+	passages, err := s.CalculateNodePassages(context.Background(), time.Date(1987, 5, 10, 0, 0, 0, 0, time.UTC), time.Date(1987, 6, 10, 0, 0, 0, 0, time.UTC), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ascending []NodePassage
+	for _, p := range passages {
+		if p.Kind == AscendingNode {
+			ascending = append(ascending, p)
+		}
+	}
+	if len(passages) != 3 || len(ascending) != 1 {
+		t.Fatalf("node passages %v, want one ascending between two descending nodes", passages)
+	}
+	if want := time.Date(1987, 5, 23, 6, 25, 0, 0, time.UTC); math.Abs(ascending[0].Time.Sub(want).Minutes()) > 2 {
+		t.Errorf("ascending node %v, want %v", ascending[0].Time, want)
+	}
+}
+
+//the major standstill of 2024/2025 with declinations of about 28.7 degrees in early 2025 and
+//the following minor standstill in 2034 with about 18.1 degrees
+func TestStandstills(t *testing.T) {
+	in := referenceInput()
+	in.DeltaT = 69.2
+	s, err := in.sampa()
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	end := time.Date(2035, 1, 1, 0, 0, 0, 0, time.UTC)
+	progress, last := monotonicProgress(t)
+	standstills, err := s.CalculateStandstills(context.Background(), start, end, progress)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(standstills) != 2 || standstills[0].Kind != MajorStandstill || standstills[1].Kind != MinorStandstill {
+		t.Fatalf("standstills %+v, want a major and a minor standstill", standstills)
+	}
+	major, minor := standstills[0], standstills[1]
+	if major.Time.Year() != 2025 || major.Time.Month() > time.March {
+		t.Errorf("major standstill epoch %v, want early 2025", major.Time)
+	}
+	if math.Abs(major.North.Declination-28.7) > 0.1 || math.Abs(major.South.Declination+28.7) > 0.1 {
+		t.Errorf("major standstill declinations %.3f and %.3f", major.North.Declination, major.South.Declination)
+	}
+	if minor.Time.Year() != 2034 || math.Abs(minor.North.Declination-18.1) > 0.1 || math.Abs(minor.South.Declination+18.1) > 0.1 {
+		t.Errorf("minor standstill %v with declinations %.3f and %.3f", minor.Time, minor.North.Declination, minor.South.Declination)
+	}
+	//one count spans the node scans and the declination scans around both epochs
+	if last[0] != last[1] || last[1] <= 2*stepCount(start, end.Add(standstillStep), standstillStep) {
+		t.Errorf("progress ended at %d of %d", last[0], last[1])
+	}
+}
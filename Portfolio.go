@@ -0,0 +1,183 @@
+package sampa
+
+import (
+	"context"
+	"errors"
+	"math"
+	"runtime"
+	"sync"
+	"time"
+)
+
+// Plant is a photovoltaic plant of a portfolio
+type Plant struct {
+	Name        string
+	Sampa       Sampa   //location, atmosphere and Bird inputs of the plant, function SampaAll, not shared with other plants
+	Capacity    float64 //power at a plane of array irradiance of 1000 W/m^2 [MW]
+	Slope       float64 //surface slope measured from the horizontal plane [degrees]
+	AzmRotation float64 //surface azimuth rotation measured from south to the projection of the surface normal, negative east [degrees]
+}
+
+// PowerSample is the power of a portfolio at one instant
+type PowerSample struct {
+	Time        time.Time
+	Power       float64 //power from the plane of array irradiance of the SUL [MW]
+	ClearPower  float64 //power without eclipse from the plane of array irradiance [MW]
+	Obscuration float64 //capacity weighted mean of the obscured area of the sun disk [percent]
+}
+
+// Ramp is the change of power between two consecutive samples
+type Ramp struct {
+	Start time.Time
+	End   time.Time
+	Rate  float64 //change of power [MW/min]
+}
+
+// RampAnalysis summarizes the ramps of a power series
+type RampAnalysis struct {
+	MaxRampUp    Ramp          //ramp with the largest increase of power
+	MaxRampDown  Ramp          //ramp with the largest decrease of power, negative rate
+	DurationUp   time.Duration //total duration of ramps above the threshold
+	DurationDown time.Duration //total duration of ramps below the negated threshold
+}
+
+func (p *Plant) validate() error {
+	if p.Sampa == nil {
+		return errors.New("missing sampa of plant " + p.Name)
+	}
+	if p.Sampa.GetFunction() != SampaAll {
+		return errors.New("plant " + p.Name + " requires function SampaAll")
+	}
+	return checkRange("capacity", p.Capacity, 0, math.Inf(1), ErrInvalidOption)
+}
+
+//plane of array irradiance from the direct normal and the diffuse and global horizontal irradiances,
+//assuming isotropic sky diffuse and ground reflected irradiance
+func (p *Plant) planeOfArray(s Sampa, dni float64, dhi float64, ghi float64) float64 {
+	zenithRad := s.GetSunZenith() * math.Pi / 180
+	slopeRad := p.Slope * math.Pi / 180
+	azimuthAstroRad := (s.GetSpaData().GetAzimuth() - 180) * math.Pi / 180
+	cosIncidence := math.Cos(zenithRad)*math.Cos(slopeRad) +
+		math.Sin(slopeRad)*math.Sin(zenithRad)*math.Cos(azimuthAstroRad-p.AzmRotation*math.Pi/180)
+	return dni*math.Max(0, cosIncidence) + dhi*(1+math.Cos(slopeRad))/2 +
+		ghi*s.GetBirdData().GetAlbedo()*(1-math.Cos(slopeRad))/2
+}
+
+//calculate the power of a plant from start to end (inclusive) in steps into series
+func (p *Plant) power(ctx context.Context, start time.Time, end time.Time, step time.Duration, series []PowerSample, pc *progressCounter) error {
+	i := 0
+	return Walk(ctx, p.Sampa, start, end, step, nil, func(s Sampa) error {
+		series[i].Time = s.GetSpaData().GetDate()
+		series[i].Power = p.Capacity * p.planeOfArray(s, s.GetDniSul(), s.GetDhiSul(), s.GetGhiSul()) / 1000
+		series[i].ClearPower = p.Capacity * p.planeOfArray(s, s.GetDni(), s.GetDhi(), s.GetGhi()) / 1000
+		series[i].Obscuration = 100 - s.GetASulPct()
+		i++
+		pc.add(1)
+		return nil
+	})
+}
+
+///////////////////////////////////////////////////////////////////////////////////////////
+// Calculate the combined power of all plants from start to end (inclusive) in steps with a
+// pool of workers (GOMAXPROCS if workers is not positive), each worker calculates whole
+// plants. The results are summed in the order of the plants, so the series does not depend
+// on the scheduling. Calculation stops with the error of ctx when it is canceled. Progress
+// counts the steps of all plants and is never called concurrently.
+///////////////////////////////////////////////////////////////////////////////////////////
+func PortfolioPower(ctx context.Context, plants []Plant, start time.Time, end time.Time, step time.Duration, workers int, progress Progress) ([]PowerSample, error) {
+	if step <= 0 {
+		return nil, &ValidationError{Field: "step", Value: step.Seconds(), Min: 1e-9, Max: math.Inf(1), Err: ErrInvalidOption}
+	}
+	for i := range plants {
+		if err := plants[i].validate(); err != nil {
+			return nil, err
+		}
+	}
+	n := stepCount(start, end, step)
+	pc := newProgressCounter(progress, n*len(plants))
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+
+	//the first failing plant cancels the remaining ones
+	workCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	results := make([][]PowerSample, len(plants))
+	errs := make([]error, len(plants))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				results[i] = make([]PowerSample, n)
+				errs[i] = plants[i].power(workCtx, start, end, step, results[i], pc)
+				if errs[i] != nil {
+					cancel()
+				}
+			}
+		}()
+	}
+	for i := range plants {
+		if workCtx.Err() != nil {
+			break
+		}
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	for _, err := range errs {
+		if err != nil && err != context.Canceled {
+			return nil, err
+		}
+	}
+
+	series := make([]PowerSample, n)
+	capacity := 0.
+	for i, t := 0, start; i < n; i, t = i+1, t.Add(step) {
+		series[i].Time = t
+	}
+	for j, p := range plants {
+		capacity += p.Capacity
+		for i, r := range results[j] {
+			series[i].Power += r.Power
+			series[i].ClearPower += r.ClearPower
+			series[i].Obscuration += p.Capacity * r.Obscuration
+		}
+	}
+	if capacity > 0 {
+		for i := range series {
+			series[i].Obscuration /= capacity
+		}
+	}
+	return series, nil
+}
+
+// AnalyzeRamps finds the largest ramps of a power series and the duration of ramps exceeding threshold [MW/min]
+func AnalyzeRamps(series []PowerSample, threshold float64) RampAnalysis {
+	var a RampAnalysis
+	for i := 1; i < len(series); i++ {
+		d := series[i].Time.Sub(series[i-1].Time)
+		if d <= 0 {
+			continue
+		}
+		r := Ramp{Start: series[i-1].Time, End: series[i].Time, Rate: (series[i].Power - series[i-1].Power) / d.Minutes()}
+		if r.Rate > a.MaxRampUp.Rate {
+			a.MaxRampUp = r
+		}
+		if r.Rate < a.MaxRampDown.Rate {
+			a.MaxRampDown = r
+		}
+		if r.Rate > threshold {
+			a.DurationUp += d
+		}
+		if r.Rate < -threshold {
+			a.DurationDown += d
+		}
+	}
+	return a
+}
@@ -0,0 +1,146 @@
+package sampa
+
+import (
+	"context"
+	"errors"
+	"math"
+	"reflect"
+	"testing"
+	"time"
+)
+
+//plants in southern Illinois and near Denver during the eclipse of 2017-08-21
+func eclipsePlants(t *testing.T) []Plant {
+	denver := illinoisEclipseInput()
+	denver.Latitude = 39.74
+	denver.Longitude = -105.18
+	var plants []Plant
+	for i, in := range []goldenInput{illinoisEclipseInput(), denver, illinoisEclipseInput()} {
+		s, err := in.sampa()
+		if err != nil {
+			t.Fatal(err)
+		}
+		plants = append(plants, Plant{Name: in.Name, Sampa: s, Capacity: float64(10 * (i + 1)), Slope: 30, AzmRotation: float64(20 * i)})
+	}
+	return plants
+}
+
+func TestPlaneOfArray(t *testing.T) {
+	s, err := illinoisEclipseInput().sampa()
+	if err != nil {
+		t.Fatal(err)
+	}
+	zenith := s.GetSunZenith()
+	cosZenith := math.Cos(zenith * math.Pi / 180)
+	dni, dhi, ghi := 800., 100., 700.
+
+	flat := Plant{}
+	if got, want := flat.planeOfArray(s, dni, dhi, ghi), dni*cosZenith+dhi; math.Abs(got-want) > 1e-9 {
+		t.Errorf("horizontal plane %.6f, want %.6f", got, want)
+	}
+	//a plane facing the sun receives the full direct normal irradiance
+	tracking := Plant{Slope: zenith, AzmRotation: s.GetSpaData().GetAzimuth() - 180}
+	want := dni + dhi*(1+cosZenith)/2 + ghi*s.GetBirdData().GetAlbedo()*(1-cosZenith)/2
+	if got := tracking.planeOfArray(s, dni, dhi, ghi); math.Abs(got-want) > 1e-9 {
+		t.Errorf("plane facing the sun %.6f, want %.6f", got, want)
+	}
+	//a plane facing away from the sun receives no direct irradiance
+	away := Plant{Slope: 90, AzmRotation: s.GetSpaData().GetAzimuth()}
+	if got := away.planeOfArray(s, dni, 0, 0); got != 0 {
+		t.Errorf("plane facing away %.6f, want 0", got)
+	}
+}
+
+func TestPortfolioPower(t *testing.T) {
+	start := time.Date(2017, 8, 21, 16, 0, 0, 0, time.UTC)
+	end := time.Date(2017, 8, 21, 19, 0, 0, 0, time.UTC)
+	plants := eclipsePlants(t)
+	var done, total int
+	progress := func(d int, t int) { done, total = d, t }
+	single, err := PortfolioPower(context.Background(), plants, start, end, 5*time.Minute, 1, progress)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(single) != 37 || done != 37*len(plants) || total != done {
+		t.Fatalf("%d samples, progress %d of %d", len(single), done, total)
+	}
+	//the sum does not depend on the number of workers
+	parallel, err := PortfolioPower(context.Background(), plants, start, end, 5*time.Minute, 3, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(single, parallel) {
+		t.Error("series differ between one and three workers")
+	}
+
+	minimum := 0
+	for i, p := range single {
+		if !p.Time.Equal(start.Add(time.Duration(i) * 5 * time.Minute)) {
+			t.Fatalf("sample %d at %v", i, p.Time)
+		}
+		if p.Power > p.ClearPower+1e-9 || p.Obscuration < -1e-9 || p.Obscuration > 100 {
+			t.Errorf("sample %d: power %.3f MW, clear %.3f MW, obscuration %.2f%%", i, p.Power, p.ClearPower, p.Obscuration)
+		}
+		if p.Power/p.ClearPower < single[minimum].Power/single[minimum].ClearPower {
+			minimum = i
+		}
+	}
+	//the eclipse of the portfolio is deepest between 18:00 and 18:30 UTC
+	if at := single[minimum].Time; at.Before(start.Add(2*time.Hour)) || at.After(start.Add(150*time.Minute)) {
+		t.Errorf("deepest eclipse at %v", at)
+	}
+	if o := single[minimum].Obscuration; o < 80 {
+		t.Errorf("maximum obscuration %.2f%%", o)
+	}
+
+	ramps := AnalyzeRamps(single, 0.1)
+	if !ramps.MaxRampDown.End.After(start) || ramps.MaxRampDown.End.After(single[minimum].Time) ||
+		ramps.MaxRampUp.Start.Before(single[minimum].Time) || ramps.MaxRampDown.Rate >= 0 || ramps.MaxRampUp.Rate <= 0 {
+		t.Errorf("ramps %+v around the minimum at %v", ramps, single[minimum].Time)
+	}
+
+	if !plants[0].Sampa.GetSpaData().GetDate().Equal(illinoisEclipseInput().date()) {
+		t.Error("spa date of a plant not restored")
+	}
+}
+
+func TestPortfolioPowerErrors(t *testing.T) {
+	start := time.Date(2017, 8, 21, 16, 0, 0, 0, time.UTC)
+	plants := eclipsePlants(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := PortfolioPower(ctx, plants, start, start.Add(time.Hour), time.Minute, 2, nil); err != context.Canceled {
+		t.Errorf("canceled portfolio returned %v", err)
+	}
+	if _, err := PortfolioPower(context.Background(), plants, start, start.Add(time.Hour), 0, 2, nil); !errors.Is(err, ErrInvalidOption) {
+		t.Errorf("zero step returned %v", err)
+	}
+	for _, capacity := range []float64{-1, math.NaN()} {
+		plants[1].Capacity = capacity
+		if _, err := PortfolioPower(context.Background(), plants, start, start.Add(time.Hour), time.Minute, 2, nil); !errors.Is(err, ErrInvalidOption) {
+			t.Errorf("capacity %v returned %v", capacity, err)
+		}
+	}
+}
+
+func TestAnalyzeRamps(t *testing.T) {
+	start := time.Date(2017, 8, 21, 16, 0, 0, 0, time.UTC)
+	powers := []float64{10, 10, 7, 1, 1, 4, 10, 10}
+	series := make([]PowerSample, len(powers))
+	for i, p := range powers {
+		series[i] = PowerSample{Time: start.Add(time.Duration(i) * 2 * time.Minute), Power: p}
+	}
+	a := AnalyzeRamps(series, 2)
+	if a.MaxRampDown.Rate != -3 || !a.MaxRampDown.Start.Equal(series[2].Time) {
+		t.Errorf("largest ramp down %+v, want -3 MW/min from sample 2", a.MaxRampDown)
+	}
+	if a.MaxRampUp.Rate != 3 || !a.MaxRampUp.Start.Equal(series[5].Time) {
+		t.Errorf("largest ramp up %+v, want 3 MW/min from sample 5", a.MaxRampUp)
+	}
+	if a.DurationDown != 2*time.Minute || a.DurationUp != 2*time.Minute {
+		t.Errorf("durations down %v, up %v, want 2m0s each", a.DurationDown, a.DurationUp)
+	}
+	if a := AnalyzeRamps(series[:1], 0); a != (RampAnalysis{}) {
+		t.Errorf("single sample ramps %+v", a)
+	}
+}
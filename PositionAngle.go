@@ -0,0 +1,118 @@
+package sampa
+
+import (
+	"math"
+)
+
+const flattenedCuspSteps = 720 //number of steps around the sun limb bracketing the cusps of flattened disks
+
+//position angle of the center of body 2 from the center of body 1, measured from celestial north through
+//east, from the topocentric right ascensions and declinations [degrees]
+func (s *sampa) positionAngle(alpha1 float64, delta1 float64, alpha2 float64, delta2 float64) float64 {
+	delta1Rad := s.deg2rad(delta1)
+	delta2Rad := s.deg2rad(delta2)
+	dAlpha := s.deg2rad(alpha2 - alpha1)
+
+	return s.limitDegrees(s.rad2deg(math.Atan2(math.Cos(delta2Rad)*math.Sin(dAlpha),
+		math.Cos(delta1Rad)*math.Sin(delta2Rad)-math.Sin(delta1Rad)*math.Cos(delta2Rad)*math.Cos(dAlpha))))
+}
+
+//parallactic angle of a body, the position angle of the zenith measured from celestial north through east,
+//from the observer latitude and the topocentric declination and local hour angle [degrees]
+func (s *sampa) parallacticAngle(latitude float64, deltaPrime float64, hPrime float64) float64 {
+	latitudeRad := s.deg2rad(latitude)
+	deltaRad := s.deg2rad(deltaPrime)
+	hRad := s.deg2rad(hPrime)
+
+	return s.limitDegrees(s.rad2deg(math.Atan2(math.Sin(hRad),
+		math.Tan(latitudeRad)*math.Cos(deltaRad)-math.Sin(deltaRad)*math.Cos(hRad))))
+}
+
+//position angle of the moon center from the sun center, measured from the zenith in the same sense as
+//the position angle from north, from the topocentric elevation and azimuth angles [degrees]
+func (s *sampa) zenithPositionAngle(eSun float64, azmSun float64, eMoon float64, azmMoon float64) float64 {
+	x, y := s.horizontalOffset(eSun, azmSun, eMoon, azmMoon)
+	return s.limitDegrees(s.rad2deg(math.Atan2(-x, y)))
+}
+
+//half of the angle subtended at the sun center by the two points where the sun and moon limbs intersect,
+//false if the limbs do not intersect [degrees]
+func (s *sampa) cuspHalfAngle(ems float64, rs float64, rm float64) (float64, bool) {
+	if ems >= rs+rm || ems <= math.Abs(rs-rm) {
+		return 0, false
+	}
+	return s.rad2deg(math.Acos((ems*ems + rs*rs - rm*rm) / (2 * ems * rs))), true
+}
+
+//intersections of the flattened sun limb with the flattened moon limb centered at the horizontal offset x, y,
+//as offsets from the sun center in the observer's horizontal plane, false unless the limbs cross twice [degrees]
+func (s *sampa) flattenedCusps(x float64, y float64, rs float64, rsVert float64, rm float64, rmVert float64) ([2][2]float64, bool) {
+	var cusps [2][2]float64
+	n := 0
+	//the moon limb equation at the point of eccentric anomaly t on the sun limb, negative inside the moon
+	limb := func(t float64) float64 {
+		u := (rs*math.Cos(t) - x) / rm
+		v := (rsVert*math.Sin(t) - y) / rmVert
+		return u*u + v*v - 1
+	}
+
+	step := 2 * math.Pi / flattenedCuspSteps
+	for i := 0; i < flattenedCuspSteps; i++ {
+		lo, hi := float64(i)*step, float64(i+1)*step
+		if (limb(lo) < 0) == (limb(hi) < 0) {
+			continue
+		}
+		if n == 2 {
+			return cusps, false
+		}
+		for j := 0; j < 50; j++ {
+			mid := (lo + hi) / 2
+			if (limb(lo) < 0) == (limb(mid) < 0) {
+				lo = mid
+			} else {
+				hi = mid
+			}
+		}
+		t := (lo + hi) / 2
+		cusps[n] = [2]float64{rs * math.Cos(t), rsVert * math.Sin(t)}
+		n++
+	}
+	return cusps, n == 2
+}
+
+///////////////////////////////////////////////////////////////////////////////////////////
+// Calculate position angles of the moon center and of the cusps (intersection points of
+// the sun and moon limbs) on the solar disk, measured from north and from zenith. With
+// disk flattening the cusps are the intersections of the flattened limbs, found in the
+// horizontal plane and rotated to north by the difference of the two moon center angles.
+///////////////////////////////////////////////////////////////////////////////////////////
+func (s *sampa) calculatePositionAngles() {
+	s.pa = s.positionAngle(s.spaData.GetAlphaPrime(), s.spaData.GetDeltaPrime(), s.mpaData.GetAlphaPrime(), s.mpaData.GetDeltaPrime())
+	s.paZenith = s.zenithPositionAngle(s.sunE, s.spaData.GetAzimuth(), s.mpaData.GetE(), s.mpaData.GetAzimuth())
+
+	s.cuspCount = 0
+	if s.diskFlattening {
+		x, y := s.horizontalOffset(s.sunE, s.spaData.GetAzimuth(), s.mpaData.GetE(), s.mpaData.GetAzimuth())
+		if cusps, ok := s.flattenedCusps(x, y, s.rs, s.rsVert, s.rm, s.rmVert); ok {
+			s.cuspCount = 2
+			for i, c := range cusps {
+				s.cuspPaZenith[i] = s.limitDegrees(s.rad2deg(math.Atan2(-c[0], c[1])))
+			}
+			//order the cusps as in the circular case, the first one before the moon center
+			if wrapDegrees(s.cuspPaZenith[0]-s.paZenith) > 0 {
+				s.cuspPaZenith[0], s.cuspPaZenith[1] = s.cuspPaZenith[1], s.cuspPaZenith[0]
+			}
+			for i := range s.cuspPa {
+				s.cuspPa[i] = s.limitDegrees(s.cuspPaZenith[i] + s.pa - s.paZenith)
+			}
+		}
+		return
+	}
+	if half, ok := s.cuspHalfAngle(s.ems, s.rs, s.rm); ok {
+		s.cuspCount = 2
+		s.cuspPa[0] = s.limitDegrees(s.pa - half)
+		s.cuspPa[1] = s.limitDegrees(s.pa + half)
+		s.cuspPaZenith[0] = s.limitDegrees(s.paZenith - half)
+		s.cuspPaZenith[1] = s.limitDegrees(s.paZenith + half)
+	}
+}
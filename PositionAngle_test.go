@@ -0,0 +1,134 @@
+package sampa
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestPositionAngles(t *testing.T) {
+	si, err := referenceInput().sampa()
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := si.(*sampa)
+	sp := s.GetSpaData()
+
+	//the zenith is at the parallactic angle q from north, seen from the sun
+	q := s.parallacticAngle(sp.GetLatitude(), sp.GetDeltaPrime(), sp.GetHPrime())
+	if d := wrapDegrees(s.GetPaZenith() - (s.GetPa() - q)); math.Abs(d) > 0.05 {
+		t.Errorf("GetPaZenith() = %.4f, GetPa() - q = %.4f", s.GetPaZenith(), s.GetPa()-q)
+	}
+
+	cusps, cuspsZenith := s.GetCuspPa(), s.GetCuspPaZenith()
+	if len(cusps) != 2 || len(cuspsZenith) != 2 {
+		t.Fatalf("GetCuspPa() = %v, GetCuspPaZenith() = %v, want two cusps", cusps, cuspsZenith)
+	}
+	if d := wrapDegrees(cusps[0]-s.GetPa()) + wrapDegrees(cusps[1]-s.GetPa()); math.Abs(d) > 1e-9 {
+		t.Errorf("cusps %v not symmetric around the position angle %.6f", cusps, s.GetPa())
+	}
+	if d := wrapDegrees(cuspsZenith[0]-s.GetPaZenith()) + wrapDegrees(cuspsZenith[1]-s.GetPaZenith()); math.Abs(d) > 1e-9 {
+		t.Errorf("cusps %v not symmetric around the position angle %.6f", cuspsZenith, s.GetPaZenith())
+	}
+
+	//the returned cusps do not change with the next calculation
+	held := cusps[0]
+	sp.SetDate(referenceInput().date().Add(10 * time.Minute))
+	if err := s.Calculate(); err != nil {
+		t.Fatal(err)
+	}
+	if cusps[0] != held || s.GetCuspPa()[0] == held {
+		t.Errorf("held cusp changed from %.6f to %.6f", held, cusps[0])
+	}
+
+	//no cusps without intersecting limbs
+	sp.SetDate(referenceInput().date().Add(6 * time.Hour))
+	if err := s.Calculate(); err != nil {
+		t.Fatal(err)
+	}
+	if len(s.GetCuspPa()) != 0 || len(s.GetCuspPaZenith()) != 0 {
+		t.Errorf("GetCuspPa() = %v without eclipse", s.GetCuspPa())
+	}
+}
+
+//Meeus, Astronomical Algorithms, example 48.a: 1992-04-12 0h TD, geocentric position angle of
+//the bright limb 285.0 degrees, topocentric values differ by the parallax of the moon
+func TestBrightLimb(t *testing.T) {
+	in := referenceInput()
+	in.DeltaT = 59
+	in.setDate(time.Date(1992, 4, 11, 23, 59, 1, 0, time.UTC))
+	for _, latitude := range []float64{-45, 0, 45} {
+		in.Latitude = latitude
+		s, err := in.sampa()
+		if err != nil {
+			t.Fatal(err)
+		}
+		m := s.GetMpaData()
+		if got := m.GetBrightLimbAngle(); math.Abs(got-285.0) > 0.5 {
+			t.Errorf("latitude %g: bright limb %.3f, want 285.0", latitude, got)
+		}
+		q := m.GetParallacticAngle()
+		if got, want := m.GetTerminatorTilt(), wrapDegrees(m.GetBrightLimbAngle()-q); got != want {
+			t.Errorf("latitude %g: terminator tilt %.3f, want %.3f", latitude, got, want)
+		}
+
+		//the zenith seen from the moon: sin q sin z = sin H cos phi, cos q sin z = sin phi cos delta - cos phi sin delta cos H
+		phi := latitude * math.Pi / 180
+		delta := m.GetDeltaPrime() * math.Pi / 180
+		h := m.GetHPrime() * math.Pi / 180
+		z := math.Acos(math.Sin(phi)*math.Sin(delta) + math.Cos(phi)*math.Cos(delta)*math.Cos(h))
+		qRad := q * math.Pi / 180
+		if math.Abs(math.Sin(qRad)*math.Sin(z)-math.Sin(h)*math.Cos(phi)) > 1e-9 ||
+			math.Abs(math.Cos(qRad)*math.Sin(z)-(math.Sin(phi)*math.Cos(delta)-math.Cos(phi)*math.Sin(delta)*math.Cos(h))) > 1e-9 {
+			t.Errorf("latitude %g: parallactic angle %.3f inconsistent with hour angle %.3f", latitude, q, m.GetHPrime())
+		}
+	}
+}
+
+//the cusps of flattened disks lie on both flattened limbs, for circular disks they are the circular cusps
+func TestFlattenedCusps(t *testing.T) {
+	si, err := sunriseEclipseInput().sampa()
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := si.(*sampa)
+	circular, circularZenith := s.GetCuspPa(), s.GetCuspPaZenith()
+	x, y := s.horizontalOffset(s.sunE, s.spaData.GetAzimuth(), s.mpaData.GetE(), s.mpaData.GetAzimuth())
+	cusps, ok := s.flattenedCusps(x, y, s.rs, s.rs, s.rm, s.rm)
+	if !ok {
+		t.Fatal("no cusps of circular disks")
+	}
+	for i, c := range cusps {
+		if d := wrapDegrees(s.rad2deg(math.Atan2(-c[0], c[1])) - circularZenith[1-i]); math.Abs(d) > 1e-3 {
+			t.Errorf("cusp %d of circular disks differs by %.6f degrees", i, d)
+		}
+	}
+
+	s.SetDiskFlattening(true)
+	if err := s.Calculate(); err != nil {
+		t.Fatal(err)
+	}
+	x, y = s.horizontalOffset(s.sunE, s.spaData.GetAzimuth(), s.mpaData.GetE(), s.mpaData.GetAzimuth())
+	flattened, flattenedZenith := s.GetCuspPa(), s.GetCuspPaZenith()
+	if len(flattened) != 2 || len(flattenedZenith) != 2 {
+		t.Fatalf("GetCuspPa() = %v with flattened disks, want two cusps", flattened)
+	}
+	for i, pa := range flattenedZenith {
+		//the cusp is where the ray from the sun center at this position angle meets the sun limb
+		dx, dy := -math.Sin(pa*math.Pi/180), math.Cos(pa*math.Pi/180)
+		k := 1 / math.Hypot(dx/s.rs, dy/s.rsVert)
+		u, v := k*dx, k*dy
+		if d := math.Hypot((u-x)/s.rm, (v-y)/s.rmVert); math.Abs(d-1) > 1e-6 {
+			t.Errorf("cusp %d at %.4f degrees not on the moon limb: %.8f", i, pa, d)
+		}
+		if d := wrapDegrees(flattened[i] - pa - s.GetPa() + s.GetPaZenith()); math.Abs(d) > 1e-9 {
+			t.Errorf("cusp %d from north %.6f, from zenith %.6f", i, flattened[i], pa)
+		}
+		if d := wrapDegrees(pa - circularZenith[i]); math.Abs(d) < 0.5 || math.Abs(d) > 5 {
+			t.Errorf("cusp %d of flattened disks at %.4f, circular %.4f", i, pa, circularZenith[i])
+		}
+	}
+	if wrapDegrees(flattened[0]-s.GetPa()) > 0 || wrapDegrees(flattened[1]-s.GetPa()) < 0 || circular[0] == flattened[0] {
+		t.Errorf("cusps %v not ordered around the position angle %.4f", flattened, s.GetPa())
+	}
+}
@@ -0,0 +1,40 @@
+package sampa
+
+import (
+	"sync"
+	"time"
+)
+
+// Progress is called by batch calculations with the number of completed and total steps, nil disables reporting
+type Progress func(done int, total int)
+
+//counts completed steps and reports them, safe for concurrent use
+type progressCounter struct {
+	mu    sync.Mutex
+	done  int
+	total int
+	fn    Progress
+}
+
+func newProgressCounter(fn Progress, total int) *progressCounter {
+	return &progressCounter{total: total, fn: fn}
+}
+
+//add completed steps and report the new count
+func (p *progressCounter) add(steps int) {
+	if p.fn == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.done += steps
+	p.fn(p.done, p.total)
+}
+
+//number of steps from start to end (inclusive)
+func stepCount(start time.Time, end time.Time, step time.Duration) int {
+	if step <= 0 || end.Before(start) {
+		return 0
+	}
+	return int(end.Sub(start)/step) + 1
+}
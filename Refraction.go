@@ -0,0 +1,202 @@
+package sampa
+
+import (
+	"github.com/maltegrosse/go-spa"
+	"math"
+)
+
+// RefractionModel defines the atmospheric refraction model applied to the sun and moon elevation angles
+type RefractionModel uint32
+
+// enumeration for atmospheric refraction models
+const (
+	RefractionSaemundsson RefractionModel = 0 //Saemundsson formula scaled by pressure and temperature, as used by SPA (default)
+	RefractionBennett     RefractionModel = 1 //Bennett formula scaled by pressure and temperature, solved for the apparent elevation
+	RefractionNone        RefractionModel = 2 //no refraction, airless elevation angles
+	RefractionRayTraced   RefractionModel = 3 //numerical ray trace through a standard atmosphere with lapse rate and humidity
+)
+
+const (
+	earthRadiusMeters  = 6378140.0 //earth equatorial radius [meters]
+	tropopauseHeight   = 11000.0   //height of the tropopause of the standard atmosphere [meters]
+	atmosphereHeight   = 80000.0   //height above which refraction is neglected [meters]
+	gravity            = 9.80665   //standard gravity [m/s^2]
+	dryAirMolarMass    = 0.0289644 //molar mass of dry air [kg/mol]
+	gasConstant        = 8.314462  //universal gas constant [J/(mol K)]
+	vapourExponent     = 18.36     //power law exponent of water vapour pressure with temperature
+	refractionWaveLen  = 0.574     //effective wavelength of visible light [micrometers]
+	rayTraceIntervals  = 64        //number of Simpson intervals per atmosphere layer
+	rayTraceIterations = 5         //iterations to solve for the apparent elevation
+)
+
+//atmospheric refraction correction of the selected model for a topocentric elevation angle (uncorrected) [degrees]
+func (s *sampa) refractionCorrection(e0 float64) float64 {
+	pressure := s.spaData.GetPressure()
+	temperature := s.spaData.GetTemperature()
+	atmosRefract := s.spaData.GetAtmosRefract()
+
+	switch s.refractionModel {
+	case RefractionBennett:
+		return s.bennettRefractionCorrection(pressure, temperature, atmosRefract, e0)
+	case RefractionNone:
+		return 0
+	case RefractionRayTraced:
+		return s.rayTracedRefractionCorrection(pressure, temperature, atmosRefract, e0)
+	}
+	return s.atmosphericRefractionCorrection(pressure, temperature, atmosRefract, e0)
+}
+
+func (s *sampa) bennettRefraction(pressure float64, temperature float64, e float64) float64 {
+	return (pressure / 1010.0) * (283.0 / (273.0 + temperature)) / (60.0 * math.Tan(s.deg2rad(e+7.31/(e+4.4))))
+}
+
+func (s *sampa) bennettRefractionCorrection(pressure float64, temperature float64, atmosRefract float64, e0 float64) float64 {
+	delE := 0.
+
+	if e0 >= -1*(spa.SunRadius+atmosRefract) {
+		// Bennett's formula takes the apparent elevation, so iterate from the geometric one
+		e := e0
+		for i := 0; i < rayTraceIterations; i++ {
+			e = e0 + s.bennettRefraction(pressure, temperature, math.Max(e, -1))
+		}
+		delE = e - e0
+	}
+	return delE
+}
+
+func (s *sampa) rayTracedRefractionCorrection(pressure float64, temperature float64, atmosRefract float64, e0 float64) float64 {
+	delE := 0.
+
+	if e0 >= -1*(spa.SunRadius+atmosRefract) {
+		atm := s.standardAtmosphere(pressure, temperature)
+		e := e0 + s.atmosphericRefractionCorrection(pressure, temperature, atmosRefract, e0)
+		for i := 0; i < rayTraceIterations; i++ {
+			e = e0 + atm.refraction(90.0-e)
+		}
+		delE = e - e0
+	}
+	return delE
+}
+
+///////////////////////////////////////////////////////////////////////////////////////////
+// Spherically layered standard atmosphere for the ray traced refraction model:
+// troposphere with constant temperature lapse rate up to the tropopause, isothermal
+// stratosphere above, water vapour pressure falling off with temperature
+///////////////////////////////////////////////////////////////////////////////////////////
+type atmosphere struct {
+	h0 float64 //observer height [meters]
+	t0 float64 //observer temperature [Kelvin]
+	p0 float64 //observer pressure [millibars]
+	w0 float64 //observer water vapour pressure [millibars]
+	ht float64 //tropopause height [meters]
+	tt float64 //tropopause temperature [Kelvin]
+	pt float64 //tropopause pressure [millibars]
+	wt float64 //tropopause water vapour pressure [millibars]
+	lr float64 //temperature lapse rate [Kelvin/meter]
+	a  float64 //dry air refractivity per pressure over temperature [Kelvin/millibar]
+}
+
+func (s *sampa) standardAtmosphere(pressure float64, temperature float64) atmosphere {
+	var atm atmosphere
+	wl2 := refractionWaveLen * refractionWaveLen
+
+	atm.h0 = s.spaData.GetElevation()
+	atm.t0 = temperature + 273.15
+	atm.p0 = pressure
+	atm.w0 = s.humidity * 6.1078 * math.Exp(17.27*temperature/(temperature+237.3))
+	atm.lr = s.lapseRate
+	atm.ht = math.Max(tropopauseHeight, atm.h0)
+	atm.a = (287.6155 + (1.62887+0.01360/wl2)/wl2) * 273.15e-6 / 1013.25
+
+	atm.tt, atm.pt, atm.wt = atm.state(atm.ht)
+	return atm
+}
+
+//temperature [Kelvin], pressure [millibars] and water vapour pressure [millibars] at height h [meters]
+func (atm *atmosphere) state(h float64) (t float64, p float64, w float64) {
+	c := gravity * dryAirMolarMass / gasConstant
+	if h <= atm.ht {
+		t = math.Max(atm.t0-atm.lr*(h-atm.h0), 100)
+		if atm.lr > 1e-9 {
+			p = atm.p0 * math.Pow(t/atm.t0, c/atm.lr)
+		} else {
+			p = atm.p0 * math.Exp(-c*(h-atm.h0)/atm.t0)
+		}
+		w = atm.w0 * math.Pow(t/atm.t0, vapourExponent)
+		return t, p, w
+	}
+	t = atm.tt
+	p = atm.pt * math.Exp(-c*(h-atm.ht)/atm.tt)
+	w = atm.wt * p / atm.pt
+	return t, p, w
+}
+
+//refractive index n at height h [meters]
+func (atm *atmosphere) n(h float64) float64 {
+	t, p, w := atm.state(h)
+	return 1 + atm.a*p/t - 11.2684e-6*w/t
+}
+
+//radial gradient of the refractive index dn/dr at height h, central difference over one meter [1/meters]
+func (atm *atmosphere) dndr(h float64) float64 {
+	return atm.n(h+0.5) - atm.n(h-0.5)
+}
+
+//height [meters] within the layer [lo, hi] where the ray with invariant c has zenith distance z [radians]
+func (atm *atmosphere) height(c float64, z float64, lo float64, hi float64) float64 {
+	sinZ := math.Sin(z)
+	h := lo
+	for i := 0; i < 20; i++ {
+		r := earthRadiusMeters + h
+		f := atm.n(h)*r*sinZ - c
+		df := (atm.n(h) + r*atm.dndr(h)) * sinZ
+		step := f / df
+		h = math.Min(math.Max(h-step, lo), hi)
+		if math.Abs(step) < 1e-4 {
+			break
+		}
+	}
+	return h
+}
+
+//integrand of the refraction integral at zenith distance z [radians] within the layer [lo, hi]
+func (atm *atmosphere) integrand(c float64, z float64, lo float64, hi float64) float64 {
+	h := atm.height(c, z, lo, hi)
+	rdn := (earthRadiusMeters + h) * atm.dndr(h)
+	return -rdn / (atm.n(h) + rdn)
+}
+
+//Simpson integration of the refraction integral between the zenith distances z1 and z2 [radians]
+func (atm *atmosphere) layer(c float64, z1 float64, z2 float64, lo float64, hi float64) float64 {
+	step := (z2 - z1) / rayTraceIntervals
+	sum := atm.integrand(c, z1, lo, hi) + atm.integrand(c, z2, lo, hi)
+	for i := 1; i < rayTraceIntervals; i++ {
+		w := 2.0
+		if i%2 == 1 {
+			w = 4.0
+		}
+		sum += w * atm.integrand(c, z1+float64(i)*step, lo, hi)
+	}
+	return sum * step / 3
+}
+
+//refraction for an apparent zenith angle z0 [degrees] at the observer [degrees]. A depressed ray
+//(z0 above 90 degrees) descends to a tangent point below the observer before it rises through the
+//same layers, so it is traced down to the tangent point and back up from the observer height
+func (atm *atmosphere) refraction(z0 float64) float64 {
+	z0Rad := z0 * math.Pi / 180
+	if z0 > 90 {
+		z1 := math.Pi - z0Rad
+		c := atm.n(atm.h0) * (earthRadiusMeters + atm.h0) * math.Sin(z1)
+		lo := atm.h0 - 2*(earthRadiusMeters+atm.h0)*(1-math.Sin(z1))
+		hTangent := atm.height(c, math.Pi/2, lo, atm.h0)
+		return 2*atm.layer(c, z1, math.Pi/2, hTangent, atm.h0)*180/math.Pi + atm.refraction(180-z0)
+	}
+	top := atm.h0 + atmosphereHeight
+	c := atm.n(atm.h0) * (earthRadiusMeters + atm.h0) * math.Sin(z0Rad)
+	zt := math.Asin(c / (atm.n(atm.ht) * (earthRadiusMeters + atm.ht)))
+	zs := math.Asin(c / (atm.n(top) * (earthRadiusMeters + top)))
+
+	r := atm.layer(c, zt, z0Rad, atm.h0, atm.ht) + atm.layer(c, zs, zt, atm.ht, top)
+	return r * 180 / math.Pi
+}
@@ -0,0 +1,62 @@
+package sampa
+
+import (
+	"math"
+	"testing"
+
+	"github.com/maltegrosse/go-spa"
+)
+
+func TestRefractionModels(t *testing.T) {
+	si, err := referenceInput().sampa()
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := si.(*sampa)
+	s.GetSpaData().SetPressure(1010)
+	s.GetSpaData().SetTemperature(10)
+
+	//near the horizon, standard refraction is about 34 arc minutes at the apparent horizon
+	for _, model := range []RefractionModel{RefractionSaemundsson, RefractionBennett, RefractionRayTraced} {
+		s.SetRefractionModel(model)
+		if got := s.refractionCorrection(-0.39); math.Abs(got-0.535) > 0.01 {
+			t.Errorf("model %d: refraction at e0 = -0.39 is %.4f, want 0.535", model, got)
+		}
+	}
+
+	//objects refracted to just below the apparent horizon are traced along depressed rays, the
+	//correction keeps growing down to the cutoff and vanishes below it
+	limit := -(spa.SunRadius + s.GetSpaData().GetAtmosRefract())
+	for _, model := range []RefractionModel{RefractionSaemundsson, RefractionBennett, RefractionRayTraced} {
+		s.SetRefractionModel(model)
+		horizon := s.refractionCorrection(-0.58)
+		depressed := s.refractionCorrection(limit + 0.01)
+		if depressed < horizon+0.04 || depressed > horizon+0.06 {
+			t.Errorf("model %d: refraction at e0 = %.2f is %.4f, %.4f at the apparent horizon", model, limit+0.01, depressed, horizon)
+		}
+		if got := s.refractionCorrection(limit - 0.01); got != 0 {
+			t.Errorf("model %d: refraction below the cutoff is %.4f, want 0", model, got)
+		}
+	}
+
+	//above 10 degrees the models agree within two arc minutes
+	for _, e0 := range []float64{10, 20, 45, 80} {
+		s.SetRefractionModel(RefractionSaemundsson)
+		want := s.refractionCorrection(e0)
+		for _, model := range []RefractionModel{RefractionBennett, RefractionRayTraced} {
+			s.SetRefractionModel(model)
+			if got := s.refractionCorrection(e0); math.Abs(got-want) > 2.0/60 {
+				t.Errorf("model %d: refraction at e0 = %g is %.4f, Saemundsson %.4f", model, e0, got, want)
+			}
+		}
+	}
+
+	s.SetRefractionModel(RefractionNone)
+	if err := s.Calculate(); err != nil {
+		t.Fatal(err)
+	}
+	m := s.GetMpaData()
+	if m.GetDelE() != 0 || m.GetE() != m.GetE0() || s.GetSunDelE() != 0 {
+		t.Errorf("RefractionNone: moon refraction %g, sun refraction %g, want 0", m.GetDelE(), s.GetSunDelE())
+	}
+}
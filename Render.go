@@ -0,0 +1,227 @@
+package sampa
+
+import (
+	"context"
+	"fmt"
+	"image"
+	"image/color"
+	"image/color/palette"
+	"image/draw"
+	"image/gif"
+	"image/png"
+	"io"
+	"math"
+	"time"
+)
+
+// Orientation defines which direction points up in rendered eclipse images
+type Orientation uint32
+
+// enumeration for the orientation of rendered eclipse images
+const (
+	OrientNorth  Orientation = 0 //celestial north up, east to the left
+	OrientZenith Orientation = 1 //observer's zenith up
+)
+
+const renderSupersampling = 3 //subpixel samples per pixel axis for antialiased edges
+
+// RenderOptions defines the layout of rendered eclipse images
+type RenderOptions struct {
+	Size        int         //width and height of the image [pixels], 256 if zero
+	SunRadius   float64     //radius of the sun disk in the image [pixels], Size/4 if zero
+	Orientation Orientation //direction pointing up in the image
+
+	Background color.Color //sky color, black if nil
+	Sun        color.Color //unshaded sun disk color, yellow if nil
+	Moon       color.Color //moon disk color outside the sun disk, dark gray if nil
+	Overlap    color.Color //color of the sun disk covered by the moon, dark blue gray if nil
+}
+
+//disk of the rendered scene in image pixels, the vertical axis rotated by rot [radians]
+type renderDisk struct {
+	x, y   float64 //center [pixels], y pointing down
+	r      float64 //horizontal radius [pixels]
+	rVert  float64 //vertical radius [pixels]
+	rot    float64 //rotation of the vertical axis from image up, counterclockwise [radians]
+	sinRot float64
+	cosRot float64
+}
+
+func (d *renderDisk) contains(px float64, py float64) bool {
+	dx := px - d.x
+	dy := d.y - py
+	u := dx*d.cosRot + dy*d.sinRot
+	v := -dx*d.sinRot + dy*d.cosRot
+	return (u*u)/(d.r*d.r)+(v*v)/(d.rVert*d.rVert) <= 1
+}
+
+func (o *RenderOptions) withDefaults() RenderOptions {
+	r := *o
+	if r.Size <= 0 {
+		r.Size = 256
+	}
+	if r.SunRadius <= 0 {
+		r.SunRadius = float64(r.Size) / 4
+	}
+	if r.Background == nil {
+		r.Background = color.RGBA{A: 255}
+	}
+	if r.Sun == nil {
+		r.Sun = color.RGBA{R: 255, G: 214, B: 64, A: 255}
+	}
+	if r.Moon == nil {
+		r.Moon = color.RGBA{R: 64, G: 64, B: 64, A: 255}
+	}
+	if r.Overlap == nil {
+		r.Overlap = color.RGBA{R: 32, G: 36, B: 56, A: 255}
+	}
+	return r
+}
+
+///////////////////////////////////////////////////////////////////////////////////////////
+// Place the sun disk in the image center and the moon disk offset by ems along the position
+// angle, both scaled from rs and rm (vertical radii rsVert and rmVert point to the zenith)
+///////////////////////////////////////////////////////////////////////////////////////////
+func renderDisks(s Sampa, opts RenderOptions) (sun renderDisk, moon renderDisk) {
+	scale := opts.SunRadius / s.GetRs()
+	angle := s.GetPa()
+	rot := s.GetPa() - s.GetPaZenith()
+	if opts.Orientation == OrientZenith {
+		angle = s.GetPaZenith()
+		rot = 0
+	}
+	angleRad := angle * math.Pi / 180
+	rotRad := rot * math.Pi / 180
+	c := float64(opts.Size) / 2
+
+	sun = renderDisk{x: c, y: c, r: s.GetRs() * scale, rVert: s.GetRsVert() * scale, rot: rotRad}
+	moon = renderDisk{
+		x:     c - s.GetEms()*scale*math.Sin(angleRad),
+		y:     c - s.GetEms()*scale*math.Cos(angleRad),
+		r:     s.GetRm() * scale,
+		rVert: s.GetRmVert() * scale,
+		rot:   rotRad,
+	}
+	sun.sinRot, sun.cosRot = math.Sin(rotRad), math.Cos(rotRad)
+	moon.sinRot, moon.cosRot = math.Sin(rotRad), math.Cos(rotRad)
+	return sun, moon
+}
+
+// RenderImage draws the sun disk, the moon disk and their overlap for the last calculated instant of s
+func RenderImage(s Sampa, opts RenderOptions) *image.RGBA {
+	o := opts.withDefaults()
+	sun, moon := renderDisks(s, o)
+	img := image.NewRGBA(image.Rect(0, 0, o.Size, o.Size))
+
+	colors := []color.RGBA{
+		color.RGBAModel.Convert(o.Background).(color.RGBA),
+		color.RGBAModel.Convert(o.Sun).(color.RGBA),
+		color.RGBAModel.Convert(o.Moon).(color.RGBA),
+		color.RGBAModel.Convert(o.Overlap).(color.RGBA),
+	}
+	samples := renderSupersampling * renderSupersampling
+
+	for py := 0; py < o.Size; py++ {
+		for px := 0; px < o.Size; px++ {
+			var sum [4]int
+			for sy := 0; sy < renderSupersampling; sy++ {
+				for sx := 0; sx < renderSupersampling; sx++ {
+					x := float64(px) + (float64(sx)+0.5)/renderSupersampling
+					y := float64(py) + (float64(sy)+0.5)/renderSupersampling
+					idx := 0
+					if sun.contains(x, y) {
+						idx = 1
+					}
+					if moon.contains(x, y) {
+						idx += 2
+					}
+					c := colors[idx]
+					sum[0] += int(c.R)
+					sum[1] += int(c.G)
+					sum[2] += int(c.B)
+					sum[3] += int(c.A)
+				}
+			}
+			img.SetRGBA(px, py, color.RGBA{
+				R: uint8(sum[0] / samples),
+				G: uint8(sum[1] / samples),
+				B: uint8(sum[2] / samples),
+				A: uint8(sum[3] / samples),
+			})
+		}
+	}
+	return img
+}
+
+// RenderPNG writes the image of RenderImage as PNG
+func RenderPNG(w io.Writer, s Sampa, opts RenderOptions) error {
+	return png.Encode(w, RenderImage(s, opts))
+}
+
+func svgColor(c color.Color) string {
+	r, g, b, a := c.RGBA()
+	return fmt.Sprintf("fill=\"#%02x%02x%02x\" fill-opacity=\"%.3f\"", r>>8, g>>8, b>>8, float64(a)/0xffff)
+}
+
+func svgEllipse(d renderDisk, attrs string) string {
+	return fmt.Sprintf("<ellipse cx=\"%.3f\" cy=\"%.3f\" rx=\"%.3f\" ry=\"%.3f\" transform=\"rotate(%.4f %.3f %.3f)\" %s/>",
+		d.x, d.y, d.r, d.rVert, -d.rot*180/math.Pi, d.x, d.y, attrs)
+}
+
+// RenderSVG writes the sun disk, the moon disk and their overlap for the last calculated instant of s as SVG
+func RenderSVG(w io.Writer, s Sampa, opts RenderOptions) error {
+	o := opts.withDefaults()
+	sun, moon := renderDisks(s, o)
+
+	_, err := fmt.Fprintf(w, "<svg xmlns=\"http://www.w3.org/2000/svg\" width=\"%d\" height=\"%d\" viewBox=\"0 0 %d %d\">\n",
+		o.Size, o.Size, o.Size, o.Size)
+	if err != nil {
+		return err
+	}
+	lines := []string{
+		"<defs><clipPath id=\"sun\">" + svgEllipse(sun, "") + "</clipPath></defs>",
+		fmt.Sprintf("<rect width=\"%d\" height=\"%d\" %s/>", o.Size, o.Size, svgColor(o.Background)),
+		svgEllipse(sun, svgColor(o.Sun)),
+		svgEllipse(moon, svgColor(o.Moon)),
+		svgEllipse(moon, svgColor(o.Overlap)+" clip-path=\"url(#sun)\""),
+		"</svg>",
+	}
+	for _, line := range lines {
+		_, err = fmt.Fprintln(w, line)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RenderFrames renders a frame sequence from start to end (inclusive) in steps, see Walk
+func RenderFrames(ctx context.Context, s Sampa, start time.Time, end time.Time, step time.Duration, opts RenderOptions, progress Progress) ([]*image.RGBA, error) {
+	var frames []*image.RGBA
+	err := Walk(ctx, s, start, end, step, progress, func(s Sampa) error {
+		frames = append(frames, RenderImage(s, opts))
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return frames, nil
+}
+
+// RenderGIF writes a frame sequence from start to end (inclusive) in steps as animated GIF with delay between frames
+// [1/100 seconds], each frame is converted to the Plan 9 palette as it is rendered, see Walk
+func RenderGIF(ctx context.Context, w io.Writer, s Sampa, start time.Time, end time.Time, step time.Duration, opts RenderOptions, delay int, progress Progress) error {
+	anim := gif.GIF{}
+	err := Walk(ctx, s, start, end, step, progress, func(s Sampa) error {
+		frame := RenderImage(s, opts)
+		p := image.NewPaletted(frame.Bounds(), palette.Plan9)
+		draw.FloydSteinberg.Draw(p, frame.Bounds(), frame, image.Point{})
+		anim.Image = append(anim.Image, p)
+		anim.Delay = append(anim.Delay, delay)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	return gif.EncodeAll(w, &anim)
+}
@@ -0,0 +1,73 @@
+package sampa
+
+import (
+	"bytes"
+	"context"
+	"image/color"
+	"image/gif"
+	"image/png"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRender(t *testing.T) {
+	s, err := referenceInput().sampa()
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts := RenderOptions{Size: 64}
+
+	var buf bytes.Buffer
+	if err := RenderPNG(&buf, s, opts); err != nil {
+		t.Fatal(err)
+	}
+	img, err := png.Decode(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b := img.Bounds(); b.Dx() != 64 || b.Dy() != 64 {
+		t.Fatalf("PNG size %v, want 64x64", b)
+	}
+	//the corner shows the sky, the sun disk is partly covered by the moon
+	if got := color.RGBAModel.Convert(img.At(0, 0)); got != (color.RGBA{A: 255}) {
+		t.Errorf("PNG corner %v, want black", got)
+	}
+	var sun, overlap int
+	for y := 0; y < 64; y++ {
+		for x := 0; x < 64; x++ {
+			switch color.RGBAModel.Convert(img.At(x, y)) {
+			case color.RGBA{R: 255, G: 214, B: 64, A: 255}:
+				sun++
+			case color.RGBA{R: 32, G: 36, B: 56, A: 255}:
+				overlap++
+			}
+		}
+	}
+	//unshaded share of the sun pixels close to the percent area of the SUL
+	if pct := 100 * float64(sun) / float64(sun+overlap); sun == 0 || overlap == 0 || pct < s.GetASulPct()-5 || pct > s.GetASulPct()+5 {
+		t.Errorf("PNG sun pixels %d, overlap pixels %d, want about %.1f%% unshaded", sun, overlap, s.GetASulPct())
+	}
+
+	buf.Reset()
+	if err := RenderSVG(&buf, s, opts); err != nil {
+		t.Fatal(err)
+	}
+	svg := buf.String()
+	if !strings.HasPrefix(svg, "<svg ") || !strings.HasSuffix(svg, "</svg>\n") || strings.Count(svg, "<ellipse") != 4 {
+		t.Errorf("SVG output:\n%s", svg)
+	}
+
+	buf.Reset()
+	start := referenceInput().date()
+	if err := RenderGIF(context.Background(), &buf, s, start, start.Add(4*time.Minute), time.Minute, opts, 10, nil); err != nil {
+		t.Fatal(err)
+	}
+	anim, err := gif.DecodeAll(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(anim.Image) != 5 || anim.Delay[0] != 10 || anim.Image[0].Bounds().Dx() != 64 {
+		t.Errorf("GIF with %d frames, delay %v", len(anim.Image), anim.Delay)
+	}
+}
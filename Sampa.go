@@ -1,9 +1,11 @@
 package sampa
 
 import (
+	"context"
 	"github.com/maltegrosse/go-bird"
 	"github.com/maltegrosse/go-spa"
 	"math"
+	"time"
 )
 
 ///////////////////////////////////////////////
@@ -85,6 +87,13 @@ const (
 
 var COUNT = 60
 
+//lunar radius ratio k (moon radius / earth equatorial radius) commonly used for eclipse geometry
+const (
+	MoonRadiusRatioSampa     = 358473400 / (6378.14 * 3600.0 * 180.0 / math.Pi) //implied by the 358473400 semidiameter constant of the original SAMPA code (default)
+	MoonRadiusRatioPenumbral = 0.2725076                                        //IAU 1982 value, used by NASA for penumbral and partial contacts
+	MoonRadiusRatioUmbral    = 0.272281                                         //used by NASA for umbral contacts of total and annular eclipses
+)
+
 const (
 	TermD     = 0
 	TermM     = 1
@@ -232,18 +241,64 @@ type Sampa interface {
 	SetSpaData(spa.Spa)
 	GetSpaData() spa.Spa
 
+	//SetBirdData copies the Bird model inputs (ozone, water, taua, ba, albedo) for the irradiance estimates,
+	//the given Bird is not modified. GetBirdData returns the Bird evaluated by the last calculation, its
+	//inputs can be changed for the next one
 	SetBirdData(bird.Bird)
 	GetBirdData() bird.Bird
 
+	//moon position of the last calculation, a copy not changed by later calculations
 	GetMpaData() Mpa
 	CalculateMpa() Mpa
+	CalculateUncertainty() (Uncertainty, error)
+	CalculateEphemeris(ctx context.Context, start time.Time, end time.Time, span time.Duration, degree int, progress Progress) (Ephemeris, error)
+	CalculateApsides(ctx context.Context, start time.Time, end time.Time, progress Progress) ([]Apsis, error)
+	CalculateMoonPhases(ctx context.Context, start time.Time, end time.Time, opts MoonPhaseOptions, progress Progress) ([]MoonPhase, error)
+	CalculateNodePassages(ctx context.Context, start time.Time, end time.Time, progress Progress) ([]NodePassage, error)
+	CalculateDeclinationExtremes(ctx context.Context, start time.Time, end time.Time, progress Progress) ([]DeclinationExtreme, error)
+	CalculateStandstills(ctx context.Context, start time.Time, end time.Time, progress Progress) ([]Standstill, error)
+	CalculateNights(ctx context.Context, start time.Time, end time.Time, progress Progress) ([]Night, error)
+	CalculateCrescentVisibility(ctx context.Context, date time.Time, progress Progress) (CrescentVisibility, error)
+	CalculateLibration() Libration
+	CalculateMoonlight() Moonlight
+	CalculateLunarIrradiance(bands []RoloBand, opts LunarIrradianceOptions) ([]LunarIrradiance, error)
+	CalculateTide() Tide
 
 	SetFunction(uint32)
 	GetFunction() uint32
 
+	//lunar radius ratio k used for the moon disk radius
+	SetMoonRadiusRatio(float64)
+	GetMoonRadiusRatio() float64
+
+	//atmospheric refraction model applied to both sun and moon elevation angles
+	SetRefractionModel(RefractionModel)
+	GetRefractionModel() RefractionModel
+	//relative humidity used by the ray traced refraction model, valid range: 0 to 1
+	SetHumidity(float64)
+	GetHumidity() float64
+	//temperature lapse rate used by the ray traced refraction model [Kelvin/meter]
+	SetLapseRate(float64)
+	GetLapseRate() float64
+	//model the sun and moon disks as ellipses flattened by differential refraction
+	SetDiskFlattening(bool)
+	GetDiskFlattening() bool
+	//interpolated moon positions used by the MPA within the covered range, nil for the periodic term series
+	SetEphemeris(Ephemeris)
+	GetEphemeris() Ephemeris
+
+	GetSunDelE() float64
+	GetSunE() float64
+	GetSunZenith() float64
 	GetEms() float64
 	GetRs() float64
 	GetRm() float64
+	GetRsVert() float64
+	GetRmVert() float64
+	GetPa() float64
+	GetPaZenith() float64
+	GetCuspPa() []float64
+	GetCuspPaZenith() []float64
 	GetASul() float64
 	GetASulPct() float64
 	GetDni() float64
@@ -259,8 +314,17 @@ func NewSampa(sp spa.Spa, bi bird.Bird) (Sampa, error) {
 
 	var sa sampa
 	sa.spaData = sp
-	sa.birdData = bi
 	sa.function = SampaAll
+	sa.moonRadiusRatio = MoonRadiusRatioSampa
+	sa.refractionModel = RefractionSaemundsson
+	sa.lapseRate = 0.0065
+	//irradiances are estimated with a private Bird, the inputs of bi are copied into it
+	irrBird, err := bird.NewBird(0, 1, 1013, 0.3, 1.5, 0.1, 0.85, 0.2, 1)
+	if err != nil {
+		return nil, err
+	}
+	sa.irrBird = irrBird
+	sa.SetBirdData(bi)
 	return &sa, sa.Calculate()
 }
 
@@ -268,18 +332,40 @@ type sampa struct {
 	spaData spa.Spa //Enter required INPUT VALUES into SPA structure (see SPA.H)
 	//spa.function will be forced to SPA_ZA, therefore slope & azm_rotation not required)
 
-	mpaData Mpa //Moon Position Algorithm structure (defined above)
+	mpaData mpa //Moon Position Algorithm structure (defined above), reused by every calculation
 
 	function uint32 //Switch to choose functions for desired output (from enumeration)
 
-	birdData bird.Bird
+	moonRadiusRatio float64 //lunar radius ratio k (moon radius / earth equatorial radius)
+
+	refractionModel RefractionModel //atmospheric refraction model for sun and moon elevation angles
+	humidity        float64         //relative humidity for the ray traced refraction model [fraction]
+	lapseRate       float64         //temperature lapse rate for the ray traced refraction model [Kelvin/meter]
+	diskFlattening  bool            //model sun and moon disks as ellipses flattened by differential refraction
+	ephemeris       Ephemeris       //interpolated moon positions, nil for the periodic term series
+
+	birdData bird.Bird //Bird model evaluated by the calculations, irrBird or nil without Bird inputs
+	irrBird  bird.Bird //private Bird model with the inputs of the caller, reused by every calculation
 
 	//---------------------Final SAMPA OUTPUT VALUES------------------------
 
+	sunDelE   float64 //atmospheric refraction correction of the sun for the selected model [degrees]
+	sunE      float64 //topocentric sun elevation angle (corrected) for the selected model [degrees]
+	sunZenith float64 //topocentric sun zenith angle for the selected model [degrees]
+
 	ems float64 //local observed, topocentric, angular distance between sun and moon centers [degrees]
 	rs  float64 //radius of sun disk [degrees]
 	rm  float64 //radius of moon disk [degrees]
 
+	rsVert float64 //apparent vertical radius of sun disk [degrees]
+	rmVert float64 //apparent vertical radius of moon disk [degrees]
+
+	pa           float64    //position angle of moon center from sun center, measured from north through east [degrees]
+	paZenith     float64    //position angle of moon center from sun center, measured from zenith [degrees]
+	cuspPa       [2]float64 //position angles of the sun and moon limb intersections on the sun disk, from north [degrees]
+	cuspPaZenith [2]float64 //position angles of the sun and moon limb intersections on the sun disk, from zenith [degrees]
+	cuspCount    int        //number of sun and moon limb intersections (0 or 2)
+
 	aSul    float64 //area of sun's unshaded lune (SUL) during eclipse [degrees squared]
 	aSulPct float64 //percent area of SUL during eclipse [percent]
 
@@ -302,7 +388,15 @@ func (s *sampa) GetSpaData() spa.Spa {
 }
 
 func (s *sampa) SetBirdData(b bird.Bird) {
-	s.birdData = b
+	s.birdData = nil
+	if b != nil {
+		s.irrBird.SetOzone(b.GetOzone())
+		s.irrBird.SetWater(b.GetWater())
+		s.irrBird.SetTaua(b.GetTaua())
+		s.irrBird.SetBa(b.GetBa())
+		s.irrBird.SetAlbedo(b.GetAlbedo())
+		s.birdData = s.irrBird
+	}
 }
 
 func (s *sampa) GetBirdData() bird.Bird {
@@ -310,7 +404,8 @@ func (s *sampa) GetBirdData() bird.Bird {
 }
 
 func (s *sampa) GetMpaData() Mpa {
-	return s.mpaData
+	m := s.mpaData
+	return &m
 }
 
 func (s *sampa) SetFunction(f uint32) {
@@ -321,6 +416,69 @@ func (s *sampa) GetFunction() uint32 {
 	return s.function
 }
 
+func (s *sampa) SetMoonRadiusRatio(k float64) {
+	s.moonRadiusRatio = k
+}
+
+func (s *sampa) GetMoonRadiusRatio() float64 {
+	return s.moonRadiusRatio
+}
+
+func (s *sampa) SetRefractionModel(model RefractionModel) {
+	s.refractionModel = model
+}
+
+func (s *sampa) GetRefractionModel() RefractionModel {
+	return s.refractionModel
+}
+
+func (s *sampa) SetHumidity(humidity float64) {
+	s.humidity = humidity
+}
+
+func (s *sampa) GetHumidity() float64 {
+	return s.humidity
+}
+
+func (s *sampa) SetLapseRate(lapseRate float64) {
+	s.lapseRate = lapseRate
+}
+
+func (s *sampa) GetLapseRate() float64 {
+	return s.lapseRate
+}
+
+func (s *sampa) SetDiskFlattening(flattening bool) {
+	s.diskFlattening = flattening
+}
+
+func (s *sampa) GetDiskFlattening() bool {
+	return s.diskFlattening
+}
+
+func (s *sampa) SetEphemeris(ephemeris Ephemeris) {
+	s.ephemeris = ephemeris
+}
+
+func (s *sampa) GetEphemeris() Ephemeris {
+	return s.ephemeris
+}
+
+//atmospheric refraction correction of the sun for the selected model [degrees]
+func (s *sampa) GetSunDelE() float64 {
+	return s.sunDelE
+}
+
+//topocentric sun elevation angle (corrected) for the selected model [degrees]
+func (s *sampa) GetSunE() float64 {
+	return s.sunE
+}
+
+//topocentric sun zenith angle for the selected model [degrees]
+func (s *sampa) GetSunZenith() float64 {
+	return s.sunZenith
+}
+
 //local observed, topocentric, angular distance between sun and moon centers [degrees]
 func (s *sampa) GetEms() float64 {
 	return s.ems
@@ -336,6 +494,38 @@ func (s *sampa) GetRm() float64 {
 	return s.rm
 }
 
+//apparent vertical radius of sun disk, equal to rs unless disk flattening is enabled [degrees]
+func (s *sampa) GetRsVert() float64 {
+	return s.rsVert
+}
+
+//apparent vertical radius of moon disk, equal to rm unless disk flattening is enabled [degrees]
+func (s *sampa) GetRmVert() float64 {
+	return s.rmVert
+}
+
+//position angle of moon center from sun center, measured from north through east [degrees]
+func (s *sampa) GetPa() float64 {
+	return s.pa
+}
+
+//position angle of moon center from sun center, measured from zenith in the same sense as from north [degrees]
+func (s *sampa) GetPaZenith() float64 {
+	return s.paZenith
+}
+
+//position angles of the contact points (intersections of sun and moon limbs) on the sun disk, measured from north,
+//empty if the limbs do not intersect, a copy not changed by later calculations [degrees]
+func (s *sampa) GetCuspPa() []float64 {
+	return append([]float64(nil), s.cuspPa[:s.cuspCount]...)
+}
+
+//position angles of the contact points (intersections of sun and moon limbs) on the sun disk, measured from zenith,
+//empty if the limbs do not intersect, a copy not changed by later calculations [degrees]
+func (s *sampa) GetCuspPaZenith() []float64 {
+	return append([]float64(nil), s.cuspPaZenith[:s.cuspCount]...)
+}
+
 //area of sun's unshaded lune (SUL) during eclipse [degrees squared]
 func (s *sampa) GetASul() float64 {
 	return s.aSul
@@ -432,6 +622,13 @@ type Mpa interface {
 	GetAzimuthAstro() float64
 	//topocentric azimuth angle (eastward from north) [for navigators and solar radiation]
 	GetAzimuth() float64
+	//parallactic angle, position angle of the zenith at the moon (eastward from north) [degrees]
+	GetParallacticAngle() float64
+	//position angle of the midpoint of the moon's bright limb (eastward from north) [degrees]
+	GetBrightLimbAngle() float64
+	//tilt of the terminator: position angle of the midpoint of the bright limb measured from the zenith,
+	//equal to the angle between the line of the cusps and the horizon, valid range: -180 to 180 [degrees]
+	GetTerminatorTilt() float64
 }
 
 // NewBird creates new Bird instance
@@ -476,6 +673,10 @@ type mpa struct {
 	zenith       float64 //topocentric zenith angle [degrees]
 	azimuthAstro float64 //topocentric azimuth angle (westward from south) [for astronomers]
 	azimuth      float64 //topocentric azimuth angle (eastward from north) [for navigators and solar radiation]
+
+	parallacticAngle float64 //parallactic angle, position angle of the zenith [degrees]
+	brightLimbAngle  float64 //position angle of the midpoint of the bright limb (eastward from north) [degrees]
+	terminatorTilt   float64 //position angle of the midpoint of the bright limb (from the zenith) [degrees]
 }
 
 func (m *mpa) GetLPrime() float64 {
@@ -582,6 +783,18 @@ func (m *mpa) GetAzimuth() float64 {
 	return m.azimuth
 }
 
+func (m *mpa) GetParallacticAngle() float64 {
+	return m.parallacticAngle
+}
+
+func (m *mpa) GetBrightLimbAngle() float64 {
+	return m.brightLimbAngle
+}
+
+func (m *mpa) GetTerminatorTilt() float64 {
+	return m.terminatorTilt
+}
+
 func (s *sampa) fourthOrderPolynomial(a float64, b float64, c float64, d float64, e float64, x float64) float64 {
 	return (((a*x+b)*x+c)*x+d)*x + e
 }
@@ -646,17 +859,31 @@ func (s *sampa) moonPeriodicTermSummation(d float64, m float64, mPrime float64,
 	}
 }
 
-func (s *sampa) moonLongitudeAndLatitude(jce float64, lPrime float64, f float64, mPrime float64, l float64, b float64, lamdaPrime *float64, beta *float64) {
+func (s *sampa) moonLongitudeAndLatitudeCorrections(jce float64, lPrime float64, f float64, mPrime float64) (deltaL float64, deltaB float64) {
 	a1 := 119.75 + 131.849*jce
 	a2 := 53.09 + 479264.290*jce
 	a3 := 313.45 + 481266.484*jce
-	deltaL := 3958*math.Sin(s.deg2rad(a1)) + 318*math.Sin(s.deg2rad(a2)) + 1962*math.Sin(s.deg2rad(lPrime-f))
-	deltaB := -2235*math.Sin(s.deg2rad(lPrime)) + 175*math.Sin(s.deg2rad(a1-f)) + 127*math.Sin(s.deg2rad(lPrime-mPrime)) + 382*math.Sin(s.deg2rad(a3)) + 175*math.Sin(s.deg2rad(a1+f)) - 115*math.Sin(s.deg2rad(lPrime+mPrime))
+	deltaL = 3958*math.Sin(s.deg2rad(a1)) + 318*math.Sin(s.deg2rad(a2)) + 1962*math.Sin(s.deg2rad(lPrime-f))
+	deltaB = -2235*math.Sin(s.deg2rad(lPrime)) + 175*math.Sin(s.deg2rad(a1-f)) + 127*math.Sin(s.deg2rad(lPrime-mPrime)) + 382*math.Sin(s.deg2rad(a3)) + 175*math.Sin(s.deg2rad(a1+f)) - 115*math.Sin(s.deg2rad(lPrime+mPrime))
+	return deltaL, deltaB
+}
+
+func (s *sampa) moonLongitudeAndLatitude(jce float64, lPrime float64, f float64, mPrime float64, l float64, b float64, lamdaPrime *float64, beta *float64) {
+	deltaL, deltaB := s.moonLongitudeAndLatitudeCorrections(jce, lPrime, f, mPrime)
 
 	*lamdaPrime = s.limitDegrees(lPrime + (l+deltaL)/1000000)
 	*beta = s.limitDegrees((b + deltaB) / 1000000)
 }
 
+//inverse of moonLongitudeAndLatitude and moonEarthDistance: the terms l, b and r of an interpolated moon position
+func (s *sampa) moonPeriodicTerms(jce float64, lPrime float64, f float64, mPrime float64, lamdaPrime float64, beta float64, capDelta float64, l *float64, b *float64, r *float64) {
+	deltaL, deltaB := s.moonLongitudeAndLatitudeCorrections(jce, lPrime, f, mPrime)
+
+	*l = wrapDegrees(lamdaPrime-lPrime)*1000000 - deltaL
+	*b = wrapDegrees(beta)*1000000 - deltaB
+	*r = (capDelta - 385000.56) * 1000
+}
+
 func (s *sampa) moonEarthDistance(r float64) float64 {
 	return 385000.56 + r/1000
 }
@@ -679,8 +906,8 @@ func (s *sampa) sunDiskRadius(r float64) float64 {
 	return 959.63 / (3600.0 * r)
 }
 
-func (s *sampa) moonDiskRadius(e float64, pi float64, capDelta float64) float64 {
-	return 358473400 * (1 + math.Sin(s.deg2rad(e))*math.Sin(s.deg2rad(pi))) / (3600.0 * capDelta)
+func (s *sampa) moonDiskRadius(e float64, pi float64, capDelta float64, k float64) float64 {
+	return 358473400 * (k / MoonRadiusRatioSampa) * (1 + math.Sin(s.deg2rad(e))*math.Sin(s.deg2rad(pi))) / (3600.0 * capDelta)
 }
 
 func (s *sampa) sulArea(ems float64, rs float64, rm float64, aSul *float64, aSulPct *float64) {
@@ -729,15 +956,21 @@ func (s *sampa) geocentricDeclination(beta float64, epsilon float64, lambda floa
 func (s *sampa) observerHourAngle(nu float64, longitude float64, alphaDeg float64) float64 {
 	return s.limitDegrees(nu + longitude - alphaDeg)
 }
+//components of the observer position at the latitude [degrees] and the elevation [meters] in equatorial earth
+//radii, x in the equatorial plane and y along the rotation axis
+func (s *sampa) observerGeocentricComponents(latitude float64, elevation float64) (float64, float64) {
+	latRad := s.deg2rad(latitude)
+	u := math.Atan(0.99664719 * math.Tan(latRad))
+	y := 0.99664719*math.Sin(u) + elevation*math.Sin(latRad)/6378140.0
+	x := math.Cos(u) + elevation*math.Cos(latRad)/6378140.0
+	return x, y
+}
 func (s *sampa) rightAscensionParallaxAndTopocentricDec(latitude float64, elevation float64, xi float64, h float64, delta float64, delAlpha *float64, deltaPrime *float64) {
 	var deltaAlphaRad float64
-	latRad := s.deg2rad(latitude)
 	xiRad := s.deg2rad(xi)
 	hRad := s.deg2rad(h)
 	deltaRad := s.deg2rad(delta)
-	u := math.Atan(0.99664719 * math.Tan(latRad))
-	y := 0.99664719*math.Sin(u) + elevation*math.Sin(latRad)/6378140.0
-	x := math.Cos(u) + elevation*math.Cos(latRad)/6378140.0
+	x, y := s.observerGeocentricComponents(latitude, elevation)
 
 	deltaAlphaRad = math.Atan2(-x*math.Sin(xiRad)*math.Sin(hRad), math.Cos(deltaRad)-x*math.Sin(xiRad)*math.Cos(hRad))
 
@@ -793,20 +1026,28 @@ func (s *sampa) topocentricAzimuthAngle(azimuthAstro float64) float64 {
 // Note: All inputs values (listed in SPA header file) must already be in structure
 ///////////////////////////////////////////////////////////////////////////////////////////
 func (m *mpa) Calculate(s *sampa) {
-	m.lPrime = s.moonMeanLongitude(s.spaData.GetJce())
-	m.d = s.moonMeanElongation(s.spaData.GetJce())
-	m.m = s.sunMeanAnomaly(s.spaData.GetJce())
-	m.mPrime = s.moonMeanAnomaly(s.spaData.GetJce())
-	m.f = s.moonLatitudeArgument(s.spaData.GetJce())
-
-	s.moonPeriodicTermSummation(m.d, m.m, m.mPrime, m.f, s.spaData.GetJce(), MlTerms, &m.l, &m.r)
-	var tmpCos float64
-	tmpCos = 0
-	s.moonPeriodicTermSummation(m.d, m.m, m.mPrime, m.f, s.spaData.GetJce(), MbTerms, &m.b, &tmpCos)
+	jce := s.spaData.GetJce()
+	m.lPrime = s.moonMeanLongitude(jce)
+	m.d = s.moonMeanElongation(jce)
+	m.m = s.sunMeanAnomaly(jce)
+	m.mPrime = s.moonMeanAnomaly(jce)
+	m.f = s.moonLatitudeArgument(jce)
+
+	if s.ephemeris != nil && s.ephemeris.Covers(jce) {
+		m.lamdaPrime = s.ephemeris.Evaluate(EphemerisMoonLongitude, jce)
+		m.beta = s.ephemeris.Evaluate(EphemerisMoonLatitude, jce)
+		m.capDelta = s.ephemeris.Evaluate(EphemerisMoonDistance, jce)
+		s.moonPeriodicTerms(jce, m.lPrime, m.f, m.mPrime, m.lamdaPrime, m.beta, m.capDelta, &m.l, &m.b, &m.r)
+	} else {
+		s.moonPeriodicTermSummation(m.d, m.m, m.mPrime, m.f, jce, MlTerms, &m.l, &m.r)
+		var tmpCos float64
+		tmpCos = 0
+		s.moonPeriodicTermSummation(m.d, m.m, m.mPrime, m.f, jce, MbTerms, &m.b, &tmpCos)
 
-	s.moonLongitudeAndLatitude(s.spaData.GetJce(), m.lPrime, m.f, m.mPrime, m.l, m.b, &m.lamdaPrime, &m.beta)
+		s.moonLongitudeAndLatitude(jce, m.lPrime, m.f, m.mPrime, m.l, m.b, &m.lamdaPrime, &m.beta)
 
-	m.capDelta = s.moonEarthDistance(m.r)
+		m.capDelta = s.moonEarthDistance(m.r)
+	}
 	m.pi = s.moonEquatorialHorizParallax(m.capDelta)
 
 	m.lamda = s.apparentMoonLongitude(m.lamdaPrime, s.spaData.GetDelPsi())
@@ -822,37 +1063,35 @@ func (m *mpa) Calculate(s *sampa) {
 	m.hPrime = s.topocentricLocalHourAngle(m.h, m.delAlpha)
 
 	m.e0 = s.topocentricElevationAngle(s.spaData.GetLatitude(), m.deltaPrime, m.hPrime)
-	m.delE = s.atmosphericRefractionCorrection(s.spaData.GetPressure(), s.spaData.GetTemperature(),
-		s.spaData.GetAtmosRefract(), m.e0)
+	m.delE = s.refractionCorrection(m.e0)
 	m.e = s.topocentricElevationAngleCorrected(m.e0, m.delE)
 
 	m.zenith = s.topocentricZenithAngle(m.e)
 	m.azimuthAstro = s.topocentricAzimuthAngleAstro(m.hPrime, s.spaData.GetLatitude(), m.deltaPrime)
 	m.azimuth = s.topocentricAzimuthAngle(m.azimuthAstro)
 
+	m.parallacticAngle = s.parallacticAngle(s.spaData.GetLatitude(), m.deltaPrime, m.hPrime)
+	m.brightLimbAngle = s.positionAngle(m.alphaPrime, m.deltaPrime, s.spaData.GetAlphaPrime(), s.spaData.GetDeltaPrime())
+	m.terminatorTilt = wrapDegrees(m.brightLimbAngle - m.parallacticAngle)
+
 }
 
 ///////////////////////////////////////////////////////////////////////////////////////////
 // Estimate solar irradiances using the SERI/NREL's Bird Clear Sky Model
+// Note: the private bird structure is updated in place with the sun geometry and the
+// unshaded lune, the Bird passed by the caller is not modified
 ///////////////////////////////////////////////////////////////////////////////////////////
 func (s *sampa) estimateIrr() error {
+	b := s.birdData
+	b.SetZenith(s.sunZenith)
+	b.SetR(s.spaData.GetR())
+	b.SetPressure(s.spaData.GetPressure())
+	b.SetDniMod(s.aSulPct / 100.0)
 
-	zenith := s.spaData.GetZenith()
-	r := s.spaData.GetR()
-
-	pressure := s.spaData.GetPressure()
-	ozone := s.birdData.GetOzone()
-	water := s.birdData.GetWater()
-	taua := s.birdData.GetTaua()
-	ba := s.birdData.GetBa()
-	albedo := s.birdData.GetAlbedo()
-	dniMod := s.aSulPct / 100.0
-
-	b, err := bird.NewBird(zenith, r, pressure, ozone, water, taua, ba, albedo, dniMod)
+	err := b.Calculate()
 	if err != nil {
 		return err
 	}
-	s.birdData = b
 	s.dni = b.GetDirectNormal()
 	s.dniSul = b.GetDirectNormalMod()
 	s.ghi = b.GetGlobalHoriz()
@@ -867,19 +1106,38 @@ func (s *sampa) estimateIrr() error {
 // Note: All inputs values (listed in SPA header file) must already be in structure
 ///////////////////////////////////////////////////////////////////////////////////////////
 func (s *sampa) Calculate() error {
+	err := s.validate()
+	if err != nil {
+		return err
+	}
 	s.spaData.SetSPAFunction(0)
 	s.spaData.SetSPAFunction(spa.SpaZa)
-	err := s.spaData.Calculate()
+	err = s.spaData.Calculate()
 	if err != nil {
 		return err
 	}
-	s.mpaData = s.CalculateMpa()
+	s.sunDelE = s.refractionCorrection(s.spaData.GetE0())
+	s.sunE = s.topocentricElevationAngleCorrected(s.spaData.GetE0(), s.sunDelE)
+	s.sunZenith = s.topocentricZenithAngle(s.sunE)
+
+	s.mpaData.Calculate(s)
 
-	s.ems = s.angularDistanceSunMoon(s.spaData.GetZenith(), s.spaData.GetAzimuth(), s.mpaData.GetZenith(), s.mpaData.GetAzimuth())
+	s.ems = s.angularDistanceSunMoon(s.sunZenith, s.spaData.GetAzimuth(), s.mpaData.GetZenith(), s.mpaData.GetAzimuth())
 	s.rs = s.sunDiskRadius(s.spaData.GetR())
-	s.rm = s.moonDiskRadius(s.mpaData.GetE(), s.mpaData.GetPi(), s.mpaData.GetCapDelta())
+	s.rm = s.moonDiskRadius(s.mpaData.GetE(), s.mpaData.GetPi(), s.mpaData.GetCapDelta(), s.moonRadiusRatio)
+
+	if s.diskFlattening {
+		s.rsVert = s.verticalDiskRadius(s.spaData.GetE0(), s.rs)
+		s.rmVert = s.verticalDiskRadius(s.mpaData.GetE0(), s.rm)
+		x, y := s.horizontalOffset(s.sunE, s.spaData.GetAzimuth(), s.mpaData.GetE(), s.mpaData.GetAzimuth())
+		s.sulAreaFlattened(x, y, s.rs, s.rsVert, s.rm, s.rmVert, &s.aSul, &s.aSulPct)
+	} else {
+		s.rsVert = s.rs
+		s.rmVert = s.rm
+		s.sulArea(s.ems, s.rs, s.rm, &s.aSul, &s.aSulPct)
+	}
 
-	s.sulArea(s.ems, s.rs, s.rm, &s.aSul, &s.aSulPct)
+	s.calculatePositionAngles()
 
 	if s.function == SampaAll {
 		err = s.estimateIrr()
@@ -0,0 +1,642 @@
+package sampa
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/maltegrosse/go-bird"
+	"github.com/maltegrosse/go-spa"
+)
+
+// The golden values were recorded from this package and are a regression snapshot, only
+// TestSampaTester checks values printed by the NREL C reference. -update replaces them with
+// the values printed by a local build of the NREL C reference (see testdata/sampa_golden.c)
+//
+//	go test -run TestGolden -update -ref /path/to/sampa_golden
+var (
+	update = flag.Bool("update", false, "regenerate testdata/golden.json from the C reference driver given by -ref")
+	ref    = flag.String("ref", "", "C reference driver used by -update")
+)
+
+const (
+	goldenFile      = "golden.json"
+	goldenTolerance = 1e-8 //relative tolerance, absolute for values below 1
+)
+
+type goldenInput struct {
+	Name         string
+	Year         int
+	Month        int
+	Day          int
+	Hour         int
+	Minute       int
+	Second       int
+	Latitude     float64
+	Longitude    float64
+	Elevation    float64
+	Pressure     float64
+	Temperature  float64
+	DeltaT       float64
+	DeltaUt1     float64
+	AtmosRefract float64
+	Ozone        float64
+	Water        float64
+	Taua         float64
+	Ba           float64
+	Albedo       float64
+}
+
+type goldenCase struct {
+	Input goldenInput
+	Mpa   map[string]float64
+	Sampa map[string]float64
+}
+
+//getters in the order printed by the C reference driver
+var goldenMpaGetters = []struct {
+	name string
+	get  func(Mpa) float64
+}{
+	{"LPrime", Mpa.GetLPrime},
+	{"D", Mpa.GetD},
+	{"M", Mpa.GetM},
+	{"MPrime", Mpa.GetMPrime},
+	{"F", Mpa.GetF},
+	{"L", Mpa.GetL},
+	{"R", Mpa.GetR},
+	{"B", Mpa.GetB},
+	{"LamdaPrime", Mpa.GetLamdaPrime},
+	{"Beta", Mpa.GetBeta},
+	{"CapDelta", Mpa.GetCapDelta},
+	{"Pi", Mpa.GetPi},
+	{"Lamda", Mpa.GetLamda},
+	{"Alpha", Mpa.GetAlpha},
+	{"Delta", Mpa.GetDelta},
+	{"H", Mpa.GetH},
+	{"DelAlpha", Mpa.GetDelAlpha},
+	{"DeltaPrime", Mpa.GetDeltaPrime},
+	{"AlphaPrime", Mpa.GetAlphaPrime},
+	{"HPrime", Mpa.GetHPrime},
+	{"E0", Mpa.GetE0},
+	{"DelE", Mpa.GetDelE},
+	{"E", Mpa.GetE},
+	{"Zenith", Mpa.GetZenith},
+	{"AzimuthAstro", Mpa.GetAzimuthAstro},
+	{"Azimuth", Mpa.GetAzimuth},
+}
+
+//getters in the order printed by the C reference driver
+var goldenSampaGetters = []struct {
+	name string
+	get  func(Sampa) float64
+}{
+	{"Ems", Sampa.GetEms},
+	{"Rs", Sampa.GetRs},
+	{"Rm", Sampa.GetRm},
+	{"ASul", Sampa.GetASul},
+	{"ASulPct", Sampa.GetASulPct},
+	{"Dni", Sampa.GetDni},
+	{"DniSul", Sampa.GetDniSul},
+	{"Ghi", Sampa.GetGhi},
+	{"GhiSul", Sampa.GetGhiSul},
+	{"Dhi", Sampa.GetDhi},
+	{"DhiSul", Sampa.GetDhiSul},
+	{"SunDelE", Sampa.GetSunDelE},
+	{"SunE", Sampa.GetSunE},
+	{"SunZenith", Sampa.GetSunZenith},
+}
+
+func (in goldenInput) date() time.Time {
+	return time.Date(in.Year, time.Month(in.Month), in.Day, in.Hour, in.Minute, in.Second, 0, time.UTC)
+}
+
+func (in *goldenInput) setDate(t time.Time) {
+	in.Year, in.Day, in.Hour, in.Minute, in.Second = t.Year(), t.Day(), t.Hour(), t.Minute(), t.Second()
+	in.Month = int(t.Month())
+}
+
+func (in goldenInput) sampa() (Sampa, error) {
+	sp, err := spa.NewSpa(in.date(), in.Latitude, in.Longitude, in.Elevation, in.Pressure, in.Temperature,
+		in.DeltaT, in.DeltaUt1, 0, 0, in.AtmosRefract)
+	if err != nil {
+		return nil, err
+	}
+	b, err := bird.NewBird(sp.GetZenith(), sp.GetR(), sp.GetPressure(), in.Ozone, in.Water, in.Taua, in.Ba, in.Albedo, 1)
+	if err != nil {
+		return nil, err
+	}
+	return NewSampa(sp, b)
+}
+
+//reference case of the NREL sampa_tester.c, also listed in the README
+func referenceInput() goldenInput {
+	return goldenInput{
+		Name:         "sampa_tester",
+		Year:         2009,
+		Month:        7,
+		Day:          22,
+		Hour:         1,
+		Minute:       33,
+		Latitude:     24.61167,
+		Longitude:    143.36167,
+		Pressure:     1000,
+		Temperature:  11,
+		DeltaT:       66.4,
+		AtmosRefract: 0.5667,
+		Ozone:        0.3,
+		Water:        1.5,
+		Taua:         0.07637,
+		Ba:           0.85,
+		Albedo:       0.2,
+	}
+}
+
+//inputs of the golden corpus: eclipses, then a grid of dates across the valid range and latitudes
+func goldenInputs() []goldenInput {
+	inputs := []goldenInput{referenceInput()}
+
+	eclipses := []struct {
+		name      string
+		date      time.Time
+		latitude  float64
+		longitude float64
+		deltaT    float64
+	}{
+		{"eclipse_1999_munich", time.Date(1999, 8, 11, 11, 3, 0, 0, time.UTC), 48.14, 11.58, 64.0},
+		{"eclipse_2006_libya", time.Date(2006, 3, 29, 10, 11, 0, 0, time.UTC), 23.15, 16.72, 64.9},
+		{"eclipse_2017_illinois", time.Date(2017, 8, 21, 18, 25, 0, 0, time.UTC), 37.58, -89.12, 68.9},
+		{"eclipse_2017_denver", time.Date(2017, 8, 21, 17, 30, 0, 0, time.UTC), 39.74, -105.18, 68.9},
+		{"eclipse_2024_dallas", time.Date(2024, 4, 8, 18, 42, 0, 0, time.UTC), 32.78, -96.80, 69.2},
+		{"eclipse_2024_newfoundland", time.Date(2024, 4, 8, 19, 55, 0, 0, time.UTC), 47.56, -52.71, 69.2},
+		{"eclipse_2021_halifax_sunrise", time.Date(2021, 6, 10, 9, 55, 0, 0, time.UTC), 44.65, -63.57, 69.4},
+		{"eclipse_2023_utah", time.Date(2023, 10, 14, 16, 35, 0, 0, time.UTC), 37.50, -110.00, 69.2},
+	}
+	for _, e := range eclipses {
+		in := referenceInput()
+		in.Name = e.name
+		in.setDate(e.date)
+		in.Latitude = e.latitude
+		in.Longitude = e.longitude
+		in.DeltaT = e.deltaT
+		in.Elevation = 250
+		inputs = append(inputs, in)
+	}
+
+	years := []int{-2000, -1500, -1000, -500, 0, 500, 1000, 1500, 1800, 1900, 1950, 2000, 2020, 2100, 2500, 3000, 4000, 5000, 6000}
+	latitudes := []float64{-89, -60, -33.87, 0, 21.3, 45.5, 66.6, 89}
+	for i, year := range years {
+		for j, latitude := range latitudes {
+			in := referenceInput()
+			in.setDate(time.Date(year, time.Month(1+(i+j)%12), 1+(3*i+j)%28, (5*i+3*j)%24, (7*i+11*j)%60, (13*i+j)%60, 0, time.UTC))
+			in.Name = fmt.Sprintf("grid_%d_%g", year, latitude)
+			in.Latitude = latitude
+			in.Longitude = -180 + math.Mod(float64(37*i+53*j), 360)
+			in.Elevation = float64((i * j * 97) % 3000)
+			in.Pressure = 1013 - in.Elevation/9
+			in.Temperature = float64((i+2*j)%40) - 10
+			in.DeltaT = math.Min(8000, 32.184+float64(year-1820)*float64(year-1820)/3000)
+			in.DeltaUt1 = float64((i+j)%9-4) / 10
+			in.Taua = 0.02 + float64((i+j)%10)*0.04
+			in.Water = 0.5 + float64(j%5)
+			inputs = append(inputs, in)
+		}
+	}
+	return inputs
+}
+
+//run the C reference driver for the golden inputs
+func goldenReference(driver string, inputs []goldenInput) ([]goldenCase, error) {
+	var stdin bytes.Buffer
+	for _, in := range inputs {
+		fmt.Fprintf(&stdin, "%d %d %d %d %d %d %g %g %g %g %g %g %g %g %g %g %g %g %g\n",
+			in.Year, in.Month, in.Day, in.Hour, in.Minute, in.Second,
+			in.DeltaUt1, in.DeltaT, in.Longitude, in.Latitude, in.Elevation, in.Pressure, in.Temperature,
+			in.AtmosRefract, in.Ozone, in.Water, in.Taua, in.Ba, in.Albedo)
+	}
+	cmd := exec.Command(driver)
+	cmd.Stdin = &stdin
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, err
+	}
+
+	var cases []goldenCase
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for _, in := range inputs {
+		if !scanner.Scan() {
+			return nil, fmt.Errorf("reference driver returned %d of %d cases", len(cases), len(inputs))
+		}
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != len(goldenMpaGetters)+len(goldenSampaGetters) {
+			return nil, fmt.Errorf("reference driver returned %d values for case %s", len(fields), in.Name)
+		}
+		values := make([]float64, len(fields))
+		for j, field := range fields {
+			values[j], err = strconv.ParseFloat(field, 64)
+			if err != nil {
+				return nil, err
+			}
+		}
+		gc := goldenCase{Input: in, Mpa: map[string]float64{}, Sampa: map[string]float64{}}
+		for j, g := range goldenMpaGetters {
+			gc.Mpa[g.name] = values[j]
+		}
+		for j, g := range goldenSampaGetters {
+			gc.Sampa[g.name] = values[len(goldenMpaGetters)+j]
+		}
+		cases = append(cases, gc)
+	}
+	return cases, scanner.Err()
+}
+
+func updateGolden(t *testing.T, path string) {
+	if *ref == "" {
+		t.Fatal("-update needs the C reference driver -ref")
+	}
+	cases, err := goldenReference(*ref, goldenInputs())
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := json.MarshalIndent(cases, "", "  ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = ioutil.WriteFile(path, append(data, '\n'), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func closeTo(got float64, want float64, tolerance float64) bool {
+	return math.Abs(got-want) <= tolerance*math.Max(1, math.Abs(want))
+}
+
+func TestGolden(t *testing.T) {
+	path := filepath.Join("testdata", goldenFile)
+	if *update {
+		updateGolden(t, path)
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var cases []goldenCase
+	err = json.Unmarshal(data, &cases)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, gc := range cases {
+		gc := gc
+		t.Run(gc.Input.Name, func(t *testing.T) {
+			s, err := gc.Input.sampa()
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, g := range goldenMpaGetters {
+				if got, want := g.get(s.GetMpaData()), gc.Mpa[g.name]; !closeTo(got, want, goldenTolerance) {
+					t.Errorf("Mpa.Get%s() = %.12f, want %.12f", g.name, got, want)
+				}
+			}
+			for _, g := range goldenSampaGetters {
+				if got, want := g.get(s), gc.Sampa[g.name]; !closeTo(got, want, goldenTolerance) {
+					t.Errorf("Get%s() = %.12f, want %.12f", g.name, got, want)
+				}
+			}
+		})
+	}
+}
+
+//values printed by the NREL sampa_tester.c, rounded as in the README
+func TestSampaTester(t *testing.T) {
+	s, err := referenceInput().sampa()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sp := s.GetSpaData()
+	tests := []struct {
+		name string
+		got  float64
+		want float64
+		tol  float64
+	}{
+		{"Julian Day", sp.GetJd(), 2455034.564583, 1e-6},
+		{"L", sp.GetL(), 299.4024, 1e-4},
+		{"B", sp.GetB(), -0.00001308059, 1e-11},
+		{"R", sp.GetR(), 1.016024, 1e-6},
+		{"H", sp.GetH(), 344.999100, 1e-6},
+		{"Delta Psi", sp.GetDelPsi(), 0.004441121, 1e-9},
+		{"Delta Epsilon", sp.GetDelEpsilon(), 0.001203311, 1e-9},
+		{"Epsilon", sp.GetEpsilon(), 23.439252, 1e-6},
+		{"Zenith", sp.GetZenith(), 14.512686, 1e-6},
+		{"Azimuth", sp.GetAzimuth(), 104.387917, 1e-6},
+		{"Angular dist", s.GetEms(), 0.374760, 1e-6},
+		{"Sun Radius", s.GetRs(), 0.262360, 1e-6},
+		{"Moon Radius", s.GetRm(), 0.283341, 1e-6},
+		{"Area unshaded", s.GetASulPct(), 78.363514, 1e-6},
+		{"DNI", s.GetDniSul(), 719.099358, 1e-6},
+	}
+	for _, tt := range tests {
+		if math.Abs(tt.got-tt.want) > tt.tol {
+			t.Errorf("%s = %.12f, want %.12f", tt.name, tt.got, tt.want)
+		}
+	}
+}
+
+func TestValidation(t *testing.T) {
+	tests := []struct {
+		name  string
+		set   func(Sampa)
+		field string
+		err   error
+	}{
+		{"function", func(s Sampa) { s.SetFunction(7) }, "function", ErrInvalidFunction},
+		{"moon radius ratio", func(s Sampa) { s.SetMoonRadiusRatio(2.7) }, "moonRadiusRatio", ErrInvalidOption},
+		{"humidity", func(s Sampa) { s.SetHumidity(50) }, "humidity", ErrInvalidOption},
+		{"taua", func(s Sampa) { s.GetBirdData().SetTaua(-1) }, "taua", ErrInvalidBird},
+		{"taua NaN", func(s Sampa) { s.GetBirdData().SetTaua(math.NaN()) }, "taua", ErrInvalidBird},
+		{"month", func(s Sampa) { s.GetSpaData().SetMonth(13) }, "month", ErrInvalidDate},
+		{"day", func(s Sampa) { s.GetSpaData().SetDay(0) }, "day", ErrInvalidDate},
+		{"minute", func(s Sampa) { s.GetSpaData().SetMinute(60) }, "minute", ErrInvalidDate},
+		{"second", func(s Sampa) { s.GetSpaData().SetSecond(60) }, "second", ErrInvalidDate},
+		{"February 31", func(s Sampa) { s.GetSpaData().SetMonth(2); s.GetSpaData().SetDay(31) }, "day", ErrInvalidDate},
+		{"February 29 2009", func(s Sampa) { s.GetSpaData().SetMonth(2); s.GetSpaData().SetDay(29) }, "day", ErrInvalidDate},
+		{"April 31", func(s Sampa) { s.GetSpaData().SetMonth(4); s.GetSpaData().SetDay(31) }, "day", ErrInvalidDate},
+		{"albedo", func(s Sampa) { s.GetBirdData().SetAlbedo(1.2) }, "albedo", ErrInvalidBird},
+		{"ba", func(s Sampa) { s.GetBirdData().SetBa(1.5) }, "ba", ErrInvalidBird},
+		{"taua dense", func(s Sampa) { s.GetBirdData().SetTaua(8) }, "taua", ErrInvalidBird},
+		{"ozone", func(s Sampa) { s.GetBirdData().SetOzone(30) }, "ozone", ErrInvalidBird},
+		{"water", func(s Sampa) { s.GetBirdData().SetWater(50) }, "water", ErrInvalidBird},
+	}
+	for _, tt := range tests {
+		s, err := referenceInput().sampa()
+		if err != nil {
+			t.Fatal(err)
+		}
+		tt.set(s)
+		err = s.Calculate()
+		var verr *ValidationError
+		if !errors.Is(err, tt.err) || !errors.As(err, &verr) || verr.Field != tt.field {
+			t.Errorf("%s: Calculate() = %v, want %v for %s", tt.name, err, tt.err, tt.field)
+		}
+	}
+}
+
+func TestDaysInMonth(t *testing.T) {
+	tests := []struct {
+		year, month, want int
+	}{
+		{2009, 2, 28},
+		{2000, 2, 29},
+		{1900, 2, 28},
+		{1500, 2, 29}, //Julian calendar
+		{-2000, 2, 29},
+		{-1, 2, 28},
+		{2009, 4, 30},
+		{2009, 12, 31},
+	}
+	for _, tt := range tests {
+		if got := daysInMonth(tt.year, tt.month); got != tt.want {
+			t.Errorf("daysInMonth(%d, %d) = %d, want %d", tt.year, tt.month, got, tt.want)
+		}
+	}
+}
+
+func TestValidationNilBird(t *testing.T) {
+	in := referenceInput()
+	sp, err := spa.NewSpa(in.date(), in.Latitude, in.Longitude, in.Elevation, in.Pressure, in.Temperature,
+		in.DeltaT, in.DeltaUt1, 0, 0, in.AtmosRefract)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewSampa(sp, nil); !errors.Is(err, ErrInvalidBird) {
+		t.Errorf("NewSampa(sp, nil) = %v, want %v", err, ErrInvalidBird)
+	}
+	s, _ := NewSampa(sp, nil)
+	s.SetFunction(SampaNoIrr)
+	if err := s.Calculate(); err != nil {
+		t.Errorf("Calculate() without Bird for SampaNoIrr = %v", err)
+	}
+}
+
+//a Bird shared by two instances is only read by their calculations
+func TestSharedBird(t *testing.T) {
+	in := referenceInput()
+	b, err := bird.NewBird(30, 1, in.Pressure, in.Ozone, in.Water, in.Taua, in.Ba, in.Albedo, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dni := b.GetDirectNormal()
+	var dniSul [2]float64
+	for i, hour := range []int{1, 4} {
+		in.Hour = hour
+		sp, err := spa.NewSpa(in.date(), in.Latitude, in.Longitude, in.Elevation, in.Pressure, in.Temperature,
+			in.DeltaT, in.DeltaUt1, 0, 0, in.AtmosRefract)
+		if err != nil {
+			t.Fatal(err)
+		}
+		s, err := NewSampa(sp, b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		dniSul[i] = s.GetDniSul()
+	}
+	if b.GetZenith() != 30 || b.GetDniMod() != 1 || b.GetDirectNormal() != dni {
+		t.Errorf("shared Bird modified: zenith %g, dniMod %g", b.GetZenith(), b.GetDniMod())
+	}
+	want, err := referenceInput().sampa()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dniSul[0] != want.GetDniSul() || dniSul[0] == dniSul[1] {
+		t.Errorf("DniSul %v with the shared Bird, want %v first", dniSul, want.GetDniSul())
+	}
+}
+
+//GetBirdData returns the Bird of the last calculation, GetMpaData a copy of the moon position
+func TestEvaluatedBirdAndMpa(t *testing.T) {
+	s, err := referenceInput().sampa()
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := s.GetBirdData()
+	if b.GetZenith() != s.GetSunZenith() || b.GetDirectNormal() != s.GetDni() || b.GetGlobalHorizMod() != s.GetGhiSul() ||
+		b.GetDniMod() != s.GetASulPct()/100 {
+		t.Errorf("Bird at zenith %g with DNI %g, want %g with %g", b.GetZenith(), b.GetDirectNormal(), s.GetSunZenith(), s.GetDni())
+	}
+	//changed inputs of the evaluated Bird are used by the next calculation
+	dni := s.GetDni()
+	b.SetTaua(0.5)
+	m := s.GetMpaData()
+	zenith := m.GetZenith()
+	s.GetSpaData().SetDate(referenceInput().date().Add(time.Hour))
+	if err := s.Calculate(); err != nil {
+		t.Fatal(err)
+	}
+	if s.GetBirdData().GetTaua() != 0.5 || s.GetDni() >= dni {
+		t.Errorf("DNI %g with taua %g after %g", s.GetDni(), s.GetBirdData().GetTaua(), dni)
+	}
+	if m.GetZenith() != zenith || s.GetMpaData().GetZenith() == zenith {
+		t.Errorf("held moon zenith changed from %g to %g", zenith, m.GetZenith())
+	}
+}
+
+//allocs/op are those of go-spa, which allocates a time zone in every spa calculation, reported as spa-allocs/op
+func benchmarkCalculate(b *testing.B, function uint32) {
+	s, err := referenceInput().sampa()
+	if err != nil {
+		b.Fatal(err)
+	}
+	s.SetFunction(function)
+	spaAllocs := testing.AllocsPerRun(10, func() { _ = s.GetSpaData().Calculate() })
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		err = s.Calculate()
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+	b.ReportMetric(spaAllocs, "spa-allocs/op")
+}
+
+func BenchmarkCalculate(b *testing.B) {
+	benchmarkCalculate(b, SampaAll)
+}
+
+func BenchmarkCalculateNoIrr(b *testing.B) {
+	benchmarkCalculate(b, SampaNoIrr)
+}
+
+func BenchmarkCalculateEphemeris(b *testing.B) {
+	s, err := referenceInput().sampa()
+	if err != nil {
+		b.Fatal(err)
+	}
+	start := referenceInput().date()
+	e, err := s.CalculateEphemeris(context.Background(), start.Add(-12*time.Hour), start.Add(12*time.Hour), 24*time.Hour, 12, nil)
+	if err != nil {
+		b.Fatal(err)
+	}
+	s.SetEphemeris(e)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		err = s.Calculate()
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+//one calculation per minute of a time series, b.N timesteps
+func BenchmarkWalk(b *testing.B) {
+	s, err := referenceInput().sampa()
+	if err != nil {
+		b.Fatal(err)
+	}
+	start := referenceInput().date()
+	b.ReportAllocs()
+	b.ResetTimer()
+	err = Walk(context.Background(), s, start, start.Add(time.Duration(b.N-1)*time.Minute), time.Minute, nil, func(Sampa) error { return nil })
+	if err != nil {
+		b.Fatal(err)
+	}
+}
+
+//go-spa allocates a time zone in every spa calculation, the SAMPA calculation adds no allocations
+func TestCalculateAllocs(t *testing.T) {
+	s, err := referenceInput().sampa()
+	if err != nil {
+		t.Fatal(err)
+	}
+	spaAllocs := testing.AllocsPerRun(100, func() { _ = s.GetSpaData().Calculate() })
+	sampaAllocs := testing.AllocsPerRun(100, func() { _ = s.Calculate() })
+	if sampaAllocs > spaAllocs {
+		t.Errorf("Calculate() allocates %v times, spa alone %v times", sampaAllocs, spaAllocs)
+	}
+}
+
+func TestEphemeris(t *testing.T) {
+	s, err := referenceInput().sampa()
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := referenceInput().date().Add(-24 * time.Hour)
+	e, err := s.CalculateEphemeris(context.Background(), start, start.Add(48*time.Hour), 12*time.Hour, 10, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e.GetSegments() != 4 || e.GetMaxError(EphemerisMoonLongitude) > 1e-8 || e.GetMaxError(EphemerisMoonDistance) > 1e-6 {
+		t.Fatalf("segments = %d, max longitude error = %g, max distance error = %g", e.GetSegments(),
+			e.GetMaxError(EphemerisMoonLongitude), e.GetMaxError(EphemerisMoonDistance))
+	}
+
+	series := s.CalculateMpa()
+	s.SetEphemeris(e)
+	cached := s.CalculateMpa()
+	tests := []struct {
+		name string
+		get  func(Mpa) float64
+	}{
+		{"LamdaPrime", Mpa.GetLamdaPrime},
+		{"Beta", Mpa.GetBeta},
+		{"L", func(m Mpa) float64 { return m.GetL() / 1e6 }},
+		{"B", func(m Mpa) float64 { return m.GetB() / 1e6 }},
+		{"Zenith", Mpa.GetZenith},
+		{"Azimuth", Mpa.GetAzimuth},
+	}
+	for _, tt := range tests {
+		if got, want := tt.get(cached), tt.get(series); !closeTo(got, want, 1e-8) {
+			t.Errorf("%s = %.12f, want %.12f", tt.name, got, want)
+		}
+	}
+	if got, want := cached.GetCapDelta(), series.GetCapDelta(); math.Abs(got-want) > 1e-6 {
+		t.Errorf("CapDelta = %.9f, want %.9f", got, want)
+	}
+
+	s.GetSpaData().SetDate(start.Add(-time.Hour))
+	s.SetEphemeris(e)
+	if err := s.Calculate(); err != nil || e.Covers(s.GetSpaData().GetJce()) {
+		t.Errorf("Calculate() outside of the ephemeris = %v, covered = %v", err, e.Covers(s.GetSpaData().GetJce()))
+	}
+}
+
+func TestMoonRadiusRatio(t *testing.T) {
+	s, err := referenceInput().sampa()
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.SetMoonRadiusRatio(MoonRadiusRatioSampa)
+	if err := s.Calculate(); err != nil {
+		t.Fatal(err)
+	}
+	rm := s.GetRm()
+	//moon radius of the original SAMPA code, listed in the README
+	if math.Abs(rm-0.283341456977) > 1e-12 {
+		t.Errorf("GetRm() = %.12f with MoonRadiusRatioSampa, want 0.283341456977", rm)
+	}
+	for _, k := range []float64{MoonRadiusRatioPenumbral, MoonRadiusRatioUmbral, 0.25} {
+		s.SetMoonRadiusRatio(k)
+		if err := s.Calculate(); err != nil {
+			t.Fatal(err)
+		}
+		if got, want := s.GetRm(), rm*k/MoonRadiusRatioSampa; !closeTo(got, want, 1e-12) {
+			t.Errorf("GetRm() = %.12f with k = %g, want %.12f", got, k, want)
+		}
+	}
+}
@@ -0,0 +1,211 @@
+package sampa
+
+import (
+	"context"
+	"math"
+	"time"
+)
+
+const searchStep = 6 * time.Hour //sampling step of searches for events of the moon
+
+//geocentric quantities of moon and sun at one instant
+type geocentric struct {
+	jce          float64 //Julian Ephemeris Century
+	moonLamda    float64 //apparent moon longitude [degrees]
+	moonBeta     float64 //moon latitude, valid range: -90 to 90 [degrees]
+	moonDistance float64 //distance between the centers of earth and moon [kilometers]
+	moonAlpha    float64 //moon right ascension [degrees]
+	moonDelta    float64 //moon declination [degrees]
+	sunLamda     float64 //apparent sun longitude [degrees]
+	sunDelta     float64 //sun declination [degrees]
+}
+
+//moon position of the periodic term series at the Julian Ephemeris Century
+func (s *sampa) moonSeriesPosition(jce float64) (lamdaPrime float64, beta float64, capDelta float64) {
+	var m mpa
+	m.lPrime = s.moonMeanLongitude(jce)
+	m.d = s.moonMeanElongation(jce)
+	m.m = s.sunMeanAnomaly(jce)
+	m.mPrime = s.moonMeanAnomaly(jce)
+	m.f = s.moonLatitudeArgument(jce)
+	s.moonPeriodicTermSummation(m.d, m.m, m.mPrime, m.f, jce, MlTerms, &m.l, &m.r)
+	var tmpCos float64
+	s.moonPeriodicTermSummation(m.d, m.m, m.mPrime, m.f, jce, MbTerms, &m.b, &tmpCos)
+	s.moonLongitudeAndLatitude(jce, m.lPrime, m.f, m.mPrime, m.l, m.b, &m.lamdaPrime, &m.beta)
+	return m.lamdaPrime, m.beta, s.moonEarthDistance(m.r)
+}
+
+//geocentric quantities at t (whole seconds) from the spa data, the moon position from the ephemeris if it covers t
+func (s *sampa) geocentricAt(t time.Time) (geocentric, error) {
+	var g geocentric
+	s.spaData.SetDate(t)
+	err := s.spaData.Calculate()
+	if err != nil {
+		return g, err
+	}
+	g.jce = s.spaData.GetJce()
+	var lamdaPrime float64
+	if s.ephemeris != nil && s.ephemeris.Covers(g.jce) {
+		lamdaPrime = s.ephemeris.Evaluate(EphemerisMoonLongitude, g.jce)
+		g.moonBeta = s.ephemeris.Evaluate(EphemerisMoonLatitude, g.jce)
+		g.moonDistance = s.ephemeris.Evaluate(EphemerisMoonDistance, g.jce)
+	} else {
+		lamdaPrime, g.moonBeta, g.moonDistance = s.moonSeriesPosition(g.jce)
+	}
+	g.moonBeta = wrapDegrees(g.moonBeta)
+	g.moonLamda = s.apparentMoonLongitude(lamdaPrime, s.spaData.GetDelPsi())
+	g.moonAlpha = s.geocentricRightAscension(g.moonLamda, s.spaData.GetEpsilon(), g.moonBeta)
+	g.moonDelta = s.geocentricDeclination(g.moonBeta, s.spaData.GetEpsilon(), g.moonLamda)
+	g.sunLamda = s.spaData.GetLamda()
+	g.sunDelta = s.spaData.GetDelta()
+	return g, nil
+}
+
+//golden section search of the minimum of f in [lo, hi] to the tolerance
+func goldenSection(f func(float64) (float64, error), lo float64, hi float64, tolerance float64) (float64, error) {
+	ratio := (math.Sqrt(5) - 1) / 2
+	c, d := hi-ratio*(hi-lo), lo+ratio*(hi-lo)
+	fc, err := f(c)
+	if err != nil {
+		return 0, err
+	}
+	fd, err := f(d)
+	if err != nil {
+		return 0, err
+	}
+	for hi-lo > tolerance {
+		if fc < fd {
+			hi, d, fd = d, c, fc
+			c = hi - ratio*(hi-lo)
+			fc, err = f(c)
+		} else {
+			lo, c, fc = c, d, fd
+			d = lo + ratio*(hi-lo)
+			fd, err = f(d)
+		}
+		if err != nil {
+			return 0, err
+		}
+	}
+	return (lo + hi) / 2, nil
+}
+
+//golden section search to one second of the minimum (or maximum) of f between a and b
+func searchExtremum(f func(time.Time) (float64, error), a time.Time, b time.Time, maximum bool) (time.Time, float64, error) {
+	sign := 1.
+	if maximum {
+		sign = -1
+	}
+	at := func(x float64) time.Time {
+		return a.Add(time.Duration(math.Round(x)) * time.Second)
+	}
+	x, err := goldenSection(func(x float64) (float64, error) {
+		v, err := f(at(x))
+		return sign * v, err
+	}, 0, b.Sub(a).Seconds(), 1)
+	if err != nil {
+		return a, 0, err
+	}
+	t := at(x)
+	v, err := f(t)
+	return t, v, err
+}
+
+//bisection to one second of the sign change of f between a and b, fa is the value at a
+func searchRoot(f func(time.Time) (float64, error), a time.Time, b time.Time, fa float64) (time.Time, error) {
+	for b.Sub(a) > time.Second {
+		m := a.Add(b.Sub(a) / 2).Truncate(time.Second)
+		if !m.After(a) {
+			break
+		}
+		fm, err := f(m)
+		if err != nil {
+			return a, err
+		}
+		if (fm < 0) == (fa < 0) {
+			a, fa = m, fm
+		} else {
+			b = m
+		}
+	}
+	return b, nil
+}
+
+//local minimum or maximum of a sampled function
+type extremum struct {
+	t       time.Time
+	v       float64
+	maximum bool
+}
+
+//local extrema of f sampled in steps from first to last, each refined to one second, pc counts the samples
+func scanExtrema(ctx context.Context, first time.Time, last time.Time, step time.Duration, pc *progressCounter, f func(time.Time) (float64, error)) ([]extremum, error) {
+	var extrema []extremum
+	var t [3]time.Time
+	var v [3]float64
+	n := 0
+	for ti := first; !ti.After(last); ti = ti.Add(step) {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		vi, err := f(ti)
+		if err != nil {
+			return nil, err
+		}
+		pc.add(1)
+		t[0], t[1], t[2] = t[1], t[2], ti
+		v[0], v[1], v[2] = v[1], v[2], vi
+		n++
+		if n < 3 {
+			continue
+		}
+		var e extremum
+		switch {
+		case v[1] < v[0] && v[1] <= v[2]:
+		case v[1] > v[0] && v[1] >= v[2]:
+			e.maximum = true
+		default:
+			continue
+		}
+		e.t, e.v, err = searchExtremum(f, t[0], t[2], e.maximum)
+		if err != nil {
+			return nil, err
+		}
+		extrema = append(extrema, e)
+	}
+	return extrema, nil
+}
+
+//sign change of a sampled function
+type root struct {
+	t      time.Time
+	rising bool //from negative to positive
+}
+
+//sign changes of f sampled in steps from first to last, each refined to one second, pc counts the samples,
+//changes by more than maxJump between two samples are discontinuities and ignored
+func scanRoots(ctx context.Context, first time.Time, last time.Time, step time.Duration, pc *progressCounter, maxJump float64, f func(time.Time) (float64, error)) ([]root, error) {
+	var roots []root
+	var prevTime time.Time
+	var prev float64
+	for ti := first; !ti.After(last); ti = ti.Add(step) {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		vi, err := f(ti)
+		if err != nil {
+			return nil, err
+		}
+		pc.add(1)
+		if ti.After(first) && (prev < 0) != (vi < 0) && math.Abs(vi-prev) < maxJump {
+			r := root{rising: vi >= 0}
+			r.t, err = searchRoot(f, prevTime, ti, prev)
+			if err != nil {
+				return nil, err
+			}
+			roots = append(roots, r)
+		}
+		prevTime, prev = ti, vi
+	}
+	return roots, nil
+}
@@ -0,0 +1,98 @@
+package sampa
+
+import (
+	"context"
+	"math"
+	"time"
+)
+
+const (
+	moonGravitationalParameter = 4.9028e12               //gravitational parameter GM of the moon [m^3/s^2]
+	sunGravitationalParameter  = 1.32712440018e20        //gravitational parameter GM of the sun [m^3/s^2]
+	astronomicalUnitMeters     = astronomicalUnit * 1000 //astronomical unit [meters]
+)
+
+// Tide is the tide-generating potential of moon and sun at the observer and the tidal accelerations
+// of a rigid earth; solid-earth corrections of gravimeters scale them by the gravimetric factor
+type Tide struct {
+	Time          time.Time
+	MoonPotential float64 //moon potential of degree 2 and 3 [m^2/s^2]
+	SunPotential  float64 //sun potential of degree 2 [m^2/s^2]
+	Potential     float64 //total tide-generating potential [m^2/s^2]
+	Vertical      float64 //tidal acceleration along the geocentric radius, positive upward [m/s^2]
+	North         float64 //horizontal tidal acceleration, positive northward [m/s^2]
+	East          float64 //horizontal tidal acceleration, positive eastward [m/s^2]
+}
+
+//geocentric latitude [degrees] and distance from the earth center [meters] of the observer at the
+//latitude [degrees] and the elevation [meters] (as the parallax of the topocentric positions)
+func (s *sampa) geocentricObserver(latitude float64, elevation float64) (float64, float64) {
+	x, y := s.observerGeocentricComponents(latitude, elevation)
+	return s.rad2deg(math.Atan2(y, x)), earthRadiusMeters * math.Hypot(x, y)
+}
+
+//potential of degree n and its derivatives by the observer distance and by the geocentric zenith angle of a body
+//with the gravitational parameter gm [m^3/s^2] at the distance d [meters], observer at the distance r [meters]
+func tidePotential(n int, gm float64, d float64, r float64, cosZ float64) (w float64, dwdr float64, dwdz float64) {
+	sinZ := math.Sqrt(math.Max(0, 1-cosZ*cosZ))
+	scale := gm / d * math.Pow(r/d, float64(n))
+	var p, dp float64
+	switch n {
+	case 2:
+		p, dp = (3*cosZ*cosZ-1)/2, 3*cosZ
+	case 3:
+		p, dp = (5*cosZ*cosZ*cosZ-3*cosZ)/2, (15*cosZ*cosZ-3)/2
+	}
+	return scale * p, float64(n) * scale * p / r, -scale * dp * sinZ
+}
+
+//tidal contribution of one body at the geocentric declination delta and hour angle h [degrees], the observer
+//at the geocentric latitude phi [degrees] and distance r [meters], adds the potential and accelerations to t
+func (s *sampa) addTide(t *Tide, gm float64, d float64, delta float64, h float64, phi float64, r float64, degree int) float64 {
+	deltaRad, hRad, phiRad := s.deg2rad(delta), s.deg2rad(h), s.deg2rad(phi)
+	cosZ := math.Sin(phiRad)*math.Sin(deltaRad) + math.Cos(phiRad)*math.Cos(deltaRad)*math.Cos(hRad)
+	//azimuth of the body eastward from north
+	azm := math.Atan2(-math.Cos(deltaRad)*math.Sin(hRad), math.Cos(phiRad)*math.Sin(deltaRad)-math.Sin(phiRad)*math.Cos(deltaRad)*math.Cos(hRad))
+	var potential float64
+	for n := 2; n <= degree; n++ {
+		w, dwdr, dwdz := tidePotential(n, gm, d, r, cosZ)
+		potential += w
+		t.Vertical += dwdr
+		//the acceleration towards the body is the decrease of the potential with the zenith angle
+		horizontal := -dwdz / r
+		t.North += horizontal * math.Cos(azm)
+		t.East += horizontal * math.Sin(azm)
+	}
+	t.Potential += potential
+	return potential
+}
+
+///////////////////////////////////////////////////////////////////////////////////////////
+// Calculate the tide-generating potential and the tidal accelerations of a rigid earth at
+// the observer for the last calculated instant: degree 2 and 3 for the moon, degree 2 for the
+// sun, from the geocentric distances, declinations and hour angles of moon and sun
+///////////////////////////////////////////////////////////////////////////////////////////
+func (s *sampa) CalculateTide() Tide {
+	t := Tide{Time: s.spaData.GetDate()}
+	m := &s.mpaData
+	phi, r := s.geocentricObserver(s.spaData.GetLatitude(), s.spaData.GetElevation())
+	t.MoonPotential = s.addTide(&t, moonGravitationalParameter, m.capDelta*1000, m.delta, m.h, phi, r, 3)
+	t.SunPotential = s.addTide(&t, sunGravitationalParameter, s.spaData.GetR()*astronomicalUnitMeters, s.spaData.GetDelta(), s.spaData.GetH(), phi, r, 2)
+	return t
+}
+
+///////////////////////////////////////////////////////////////////////////////////////////
+// Calculate the tides of s from start to end (inclusive) in steps. The date of the spa data
+// and the outputs of s are restored afterwards.
+///////////////////////////////////////////////////////////////////////////////////////////
+func TideSeries(ctx context.Context, s Sampa, start time.Time, end time.Time, step time.Duration, progress Progress) ([]Tide, error) {
+	var tides []Tide
+	err := Walk(ctx, s, start, end, step, progress, func(s Sampa) error {
+		tides = append(tides, s.CalculateTide())
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return tides, nil
+}
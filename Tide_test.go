@@ -0,0 +1,76 @@
+package sampa
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+)
+
+const microGal = 1e-8 //[m/s^2]
+
+//rigid earth tides: the moon at its mean distance lifts by 2 GM r / d^3 = 110 microGal below it and pulls
+//down by half of that at the horizon, the sun adds about 50 microGal (Longman 1959)
+func TestTidePotential(t *testing.T) {
+	tests := []struct {
+		name string
+		gm   float64
+		d    float64
+		cosZ float64
+		want float64
+	}{
+		{"moon at the zenith", moonGravitationalParameter, moonMeanDistance * 1000, 1, 110},
+		{"moon at the horizon", moonGravitationalParameter, moonMeanDistance * 1000, 0, -55},
+		{"sun at the zenith", sunGravitationalParameter, astronomicalUnitMeters, 1, 50.6},
+	}
+	for _, test := range tests {
+		_, dwdr, dwdz := tidePotential(2, test.gm, test.d, earthRadiusMeters, test.cosZ)
+		if got := dwdr / microGal; math.Abs(got-test.want) > 1 {
+			t.Errorf("%s: vertical %.2f microGal, want %.1f", test.name, got, test.want)
+		}
+		if dwdz != 0 {
+			t.Errorf("%s: horizontal %g, want 0", test.name, dwdz)
+		}
+	}
+	//the horizontal acceleration is largest at 45 degrees: 3/2 GM r / d^3 = 82.5 microGal
+	_, _, dwdz := tidePotential(2, moonGravitationalParameter, moonMeanDistance*1000, earthRadiusMeters, math.Sqrt(0.5))
+	if got := -dwdz / earthRadiusMeters / microGal; math.Abs(got-82.5) > 1 {
+		t.Errorf("horizontal at 45 degrees %.2f microGal, want 82.5", got)
+	}
+}
+
+//a month of combined tides at the reference site stays within about +180 and -95 microGal
+//with the perigee full moon of 2016-11-14
+func TestTideSeries(t *testing.T) {
+	in := referenceInput()
+	in.DeltaT = 68.2
+	in.setDate(time.Date(2016, 11, 1, 0, 0, 0, 0, time.UTC))
+	s, err := in.sampa()
+	if err != nil {
+		t.Fatal(err)
+	}
+	progress, last := monotonicProgress(t)
+	tides, err := TideSeries(context.Background(), s, in.date(), in.date().AddDate(0, 1, 0), 10*time.Minute, progress)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tides) != 30*144+1 || last[0] != len(tides) {
+		t.Fatalf("%d tides, progress %d of %d", len(tides), last[0], last[1])
+	}
+	high, low := math.Inf(-1), math.Inf(1)
+	for _, tide := range tides {
+		high, low = math.Max(high, tide.Vertical), math.Min(low, tide.Vertical)
+		if math.Abs(tide.Potential-tide.MoonPotential-tide.SunPotential) > 1e-9 {
+			t.Fatalf("%v: potential %g, moon %g, sun %g", tide.Time, tide.Potential, tide.MoonPotential, tide.SunPotential)
+		}
+		if h := math.Hypot(tide.North, tide.East) / microGal; h > 150 {
+			t.Fatalf("%v: horizontal %.1f microGal", tide.Time, h)
+		}
+	}
+	if high/microGal < 160 || high/microGal > 200 || low/microGal < -110 || low/microGal > -80 {
+		t.Errorf("vertical range %.1f to %.1f microGal", high/microGal, low/microGal)
+	}
+	if !s.GetSpaData().GetDate().Equal(in.date()) {
+		t.Errorf("spa date %v not restored", s.GetSpaData().GetDate())
+	}
+}
@@ -0,0 +1,171 @@
+package sampa
+
+import (
+	"math"
+	"time"
+)
+
+//documented uncertainties of the position algorithms
+const (
+	SunPositionUncertainty  = 0.0003 //uncertainty of the SPA sun position [degrees]
+	MoonPositionUncertainty = 0.003  //uncertainty of the MPA moon position [degrees]
+)
+
+const (
+	uncertaintyEmsStep  = 1e-4 //step of the separation for the area sensitivity [degrees]
+	uncertaintyTimeStep = 30   //step of the date for the separation rate [seconds]
+)
+
+// Uncertainty interface defines the standard uncertainties of the SAMPA outputs
+type Uncertainty interface {
+	//uncertainty of delta T [seconds]
+	GetDeltaT() float64
+	//uncertainty of the angular distance between sun and moon centers [degrees]
+	GetEms() float64
+	//uncertainty of the percent area of SUL [percent]
+	GetASulPct() float64
+	//uncertainty of the direct normal irradiance from the SUL, NaN unless the function is SampaAll [W/m^2]
+	GetDniSul() float64
+	//rate of change of the angular distance between sun and moon centers [degrees/second]
+	GetEmsRate() float64
+	//uncertainty of the time of eclipse contacts near this instant, infinite if the separation is stationary [seconds]
+	GetContactTime() float64
+}
+
+type uncertainty struct {
+	deltaT      float64 //uncertainty of delta T [seconds]
+	ems         float64 //uncertainty of the angular distance between sun and moon centers [degrees]
+	aSulPct     float64 //uncertainty of the percent area of SUL [percent]
+	dniSul      float64 //uncertainty of the direct normal irradiance from the SUL [W/m^2]
+	emsRate     float64 //rate of change of the angular distance between sun and moon centers [degrees/second]
+	contactTime float64 //uncertainty of the time of eclipse contacts [seconds]
+}
+
+func (u *uncertainty) GetDeltaT() float64 {
+	return u.deltaT
+}
+
+func (u *uncertainty) GetEms() float64 {
+	return u.ems
+}
+
+func (u *uncertainty) GetASulPct() float64 {
+	return u.aSulPct
+}
+
+func (u *uncertainty) GetDniSul() float64 {
+	return u.dniSul
+}
+
+func (u *uncertainty) GetEmsRate() float64 {
+	return u.emsRate
+}
+
+func (u *uncertainty) GetContactTime() float64 {
+	return u.contactTime
+}
+
+// DeltaTUncertainty estimates the standard uncertainty of delta T for a (fractional) year [seconds]:
+// 0.8 t^2 seconds with t in centuries from 1820 before the telescopic observations (after Morrison
+// and Stephenson, 2004), 0.1 seconds from there to 2030 and for the extrapolation beyond 2030 the
+// same parabola shifted to continue from 0.1 seconds, so the estimate has no steps
+func DeltaTUncertainty(year float64) float64 {
+	t := (year - 1820) / 100
+	switch {
+	case year < 1820:
+		return math.Max(0.1, 0.8*t*t)
+	case year > 2030:
+		return 0.1 + 0.8*(t*t-2.1*2.1)
+	}
+	return 0.1
+}
+
+//fractional year of a date
+func fractionalYear(t time.Time) float64 {
+	start := time.Date(t.Year(), 1, 1, 0, 0, 0, 0, t.Location())
+	end := time.Date(t.Year()+1, 1, 1, 0, 0, 0, 0, t.Location())
+	return float64(t.Year()) + float64(t.Sub(start))/float64(end.Sub(start))
+}
+
+///////////////////////////////////////////////////////////////////////////////////////////
+// Propagate the position uncertainties of sun and moon and the uncertainty of delta T to the
+// SAMPA outputs by linearized sensitivity: the separation is recalculated with delta T and
+// the date perturbed, the area of the SUL (of the flattened disks if enabled) is
+// differentiated with respect to the separation. The SAMPA outputs of the current inputs
+// are restored afterwards.
+///////////////////////////////////////////////////////////////////////////////////////////
+func (s *sampa) CalculateUncertainty() (result Uncertainty, err error) {
+	var u uncertainty
+	sp := s.spaData
+	deltaT := sp.GetDeltaT()
+	date := sp.GetDate()
+	function := s.function
+	ems, rs, rm, dni := s.ems, s.rs, s.rm, s.dni
+	rsVert, rmVert := s.rsVert, s.rmVert
+	x, y := s.horizontalOffset(s.sunE, sp.GetAzimuth(), s.mpaData.GetE(), s.mpaData.GetAzimuth())
+
+	defer restoreDate(s, &err)()
+	//runs first, the outputs are recalculated with the restored inputs
+	defer func() {
+		sp.SetDeltaT(deltaT)
+		s.function = function
+	}()
+	s.function = SampaNoIrr
+
+	u.deltaT = DeltaTUncertainty(fractionalYear(date))
+	emsAt := func(dt float64, offset time.Duration) (float64, error) {
+		sp.SetDeltaT(math.Max(-8000, math.Min(8000, deltaT+dt)))
+		sp.SetDate(date.Add(offset))
+		err := s.Calculate()
+		return s.ems, err
+	}
+
+	emsPlus, err := emsAt(u.deltaT, 0)
+	if err != nil {
+		return nil, err
+	}
+	emsMinus, err := emsAt(-u.deltaT, 0)
+	if err != nil {
+		return nil, err
+	}
+	emsDeltaT := (emsPlus - emsMinus) / 2
+
+	emsLater, err := emsAt(0, uncertaintyTimeStep*time.Second)
+	if err != nil {
+		return nil, err
+	}
+	emsEarlier, err := emsAt(0, -uncertaintyTimeStep*time.Second)
+	if err != nil {
+		return nil, err
+	}
+	u.emsRate = (emsLater - emsEarlier) / (2 * uncertaintyTimeStep)
+
+	u.ems = math.Sqrt(SunPositionUncertainty*SunPositionUncertainty +
+		MoonPositionUncertainty*MoonPositionUncertainty + emsDeltaT*emsDeltaT)
+
+	//percent area of SUL at the separation d, the flattened disks are moved along their offset
+	pctAt := func(d float64) float64 {
+		var aSul, pct float64
+		if !s.diskFlattening {
+			s.sulArea(d, rs, rm, &aSul, &pct)
+		} else if ems > 0 {
+			s.sulAreaFlattened(x*d/ems, y*d/ems, rs, rsVert, rm, rmVert, &aSul, &pct)
+		} else {
+			s.sulAreaFlattened(0, d, rs, rsVert, rm, rmVert, &aSul, &pct)
+		}
+		return pct
+	}
+	pctPlus := pctAt(ems + uncertaintyEmsStep)
+	pctMinus := pctAt(math.Max(0, ems-uncertaintyEmsStep))
+	u.aSulPct = math.Abs(pctPlus-pctMinus) / (ems + uncertaintyEmsStep - math.Max(0, ems-uncertaintyEmsStep)) * u.ems
+	u.dniSul = math.NaN()
+	if function == SampaAll {
+		u.dniSul = dni * u.aSulPct / 100.0
+	}
+
+	u.contactTime = math.Inf(1)
+	if u.emsRate != 0 {
+		u.contactTime = u.ems / math.Abs(u.emsRate)
+	}
+	return &u, nil
+}
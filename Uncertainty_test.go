@@ -0,0 +1,110 @@
+package sampa
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestUncertainty(t *testing.T) {
+	s, err := referenceInput().sampa()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sp := s.GetSpaData()
+	date, deltaT, ems, pct := sp.GetDate(), sp.GetDeltaT(), s.GetEms(), s.GetASulPct()
+
+	u, err := s.CalculateUncertainty()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !sp.GetDate().Equal(date) || sp.GetDeltaT() != deltaT || s.GetEms() != ems || s.GetASulPct() != pct {
+		t.Errorf("inputs or outputs not restored: date %v, delta T %g, ems %g, area %g", sp.GetDate(), sp.GetDeltaT(), s.GetEms(), s.GetASulPct())
+	}
+	if u.GetDeltaT() != 0.1 || u.GetEms() < MoonPositionUncertainty || u.GetASulPct() <= 0 || u.GetEmsRate() == 0 ||
+		math.IsInf(u.GetContactTime(), 0) {
+		t.Errorf("2009: delta T %g, ems %g, area %g, ems rate %g, contact time %g", u.GetDeltaT(), u.GetEms(),
+			u.GetASulPct(), u.GetEmsRate(), u.GetContactTime())
+	}
+
+	//the delta T uncertainty grows quadratically away from the observed period
+	previous := u.GetDeltaT()
+	for _, year := range []int{1700, 1000, 0, -1000} {
+		sp.SetDate(time.Date(year, 7, 22, 1, 33, 0, 0, time.UTC))
+		h, err := s.CalculateUncertainty()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if h.GetDeltaT() <= previous || h.GetEms() < u.GetEms() {
+			t.Errorf("year %d: delta T uncertainty %g after %g, ems uncertainty %g", year, h.GetDeltaT(), previous, h.GetEms())
+		}
+		previous = h.GetDeltaT()
+	}
+	if got := DeltaTUncertainty(1000); math.Abs(got-0.8*8.2*8.2) > 1e-9 {
+		t.Errorf("DeltaTUncertainty(1000) = %g, want %g", got, 0.8*8.2*8.2)
+	}
+	if !(u.GetDniSul() > 0) {
+		t.Errorf("SampaAll: DNI uncertainty %g", u.GetDniSul())
+	}
+
+	//without irradiances there is no DNI uncertainty
+	s.SetFunction(SampaNoIrr)
+	sp.SetDate(date)
+	if err := s.Calculate(); err != nil {
+		t.Fatal(err)
+	}
+	if h, err := s.CalculateUncertainty(); err != nil || !math.IsNaN(h.GetDniSul()) {
+		t.Errorf("SampaNoIrr: DNI uncertainty %v, %v, want NaN", h, err)
+	}
+}
+
+//the delta T uncertainty has no steps and does not decrease away from the observed period
+func TestDeltaTUncertaintyContinuous(t *testing.T) {
+	previous := DeltaTUncertainty(-2000)
+	for year := -2000.; year <= 1900; year += 0.25 {
+		got := DeltaTUncertainty(year)
+		if got > previous || previous-got > 0.5 {
+			t.Fatalf("year %g: %g after %g", year, got, previous)
+		}
+		previous = got
+	}
+	for year := 1900.; year <= 6000; year += 0.25 {
+		got := DeltaTUncertainty(year)
+		if got < previous || got-previous > 0.5 {
+			t.Fatalf("year %g: %g after %g", year, got, previous)
+		}
+		previous = got
+	}
+	for _, year := range []float64{1820, 1955, 2030} {
+		if d := math.Abs(DeltaTUncertainty(year+1e-6) - DeltaTUncertainty(year-1e-6)); d > 1e-6 {
+			t.Errorf("step of %g seconds at %g", d, year)
+		}
+	}
+}
+
+//at sunrise the area sensitivity of the flattened disks differs from that of circular disks
+func TestUncertaintyFlattened(t *testing.T) {
+	s, err := sunriseEclipseInput().sampa()
+	if err != nil {
+		t.Fatal(err)
+	}
+	circular, err := s.CalculateUncertainty()
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.SetDiskFlattening(true)
+	if err := s.Calculate(); err != nil {
+		t.Fatal(err)
+	}
+	pct := s.GetASulPct()
+	flattened, err := s.CalculateUncertainty()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.GetASulPct() != pct {
+		t.Errorf("flattened area %g not restored to %g", s.GetASulPct(), pct)
+	}
+	if d := flattened.GetASulPct() - circular.GetASulPct(); d == 0 || math.Abs(d) > 0.05*circular.GetASulPct() {
+		t.Errorf("area uncertainty %.6f%% of flattened disks, %.6f%% of circular disks", flattened.GetASulPct(), circular.GetASulPct())
+	}
+}
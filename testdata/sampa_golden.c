@@ -0,0 +1,59 @@
+/////////////////////////////////////////////////////////////////////////////
+// Golden value driver for the NREL SAMPA C reference (sampa.c, spa.c).
+//
+// Reads one case per line from stdin:
+//   year month day hour minute second delta_ut1 delta_t longitude latitude
+//   elevation pressure temperature atmos_refract ozone water taua ba albedo
+// and prints one line per case: all MPA values followed by the SAMPA
+// outputs and the refracted sun elevation, in the order expected by
+// Sampa_test.go.
+//
+// Build next to the NREL sources (https://midcdmz.nrel.gov/sampa):
+//   cc -O2 -o sampa_golden sampa_golden.c sampa.c spa.c -lm
+// and regenerate the golden values from the module root:
+//   go test -run TestGolden -update -ref /path/to/sampa_golden
+/////////////////////////////////////////////////////////////////////////////
+
+#include <stdio.h>
+#include "sampa.h"
+
+int main(void)
+{
+    sampa_data sampa;
+    int result;
+
+    while (scanf("%d %d %d %d %d %lf %lf %lf %lf %lf %lf %lf %lf %lf %lf %lf %lf %lf %lf",
+                 &sampa.spa.year, &sampa.spa.month, &sampa.spa.day, &sampa.spa.hour,
+                 &sampa.spa.minute, &sampa.spa.second, &sampa.spa.delta_ut1, &sampa.spa.delta_t,
+                 &sampa.spa.longitude, &sampa.spa.latitude, &sampa.spa.elevation,
+                 &sampa.spa.pressure, &sampa.spa.temperature, &sampa.spa.atmos_refract,
+                 &sampa.bird_ozone, &sampa.bird_pwv, &sampa.bird_aod, &sampa.bird_ba,
+                 &sampa.bird_albedo) == 19)
+    {
+        sampa.spa.timezone = 0.0;
+        sampa.bird_sol_con = 1367.0;
+        sampa.function = SAMPA_ALL;
+
+        result = sampa_calculate(&sampa);
+        if (result != 0) {
+            fprintf(stderr, "sampa_calculate error code: %d\n", result);
+            return result;
+        }
+
+        printf("%.17g %.17g %.17g %.17g %.17g %.17g %.17g %.17g %.17g %.17g %.17g %.17g %.17g ",
+               sampa.mpa.l_prime, sampa.mpa.d, sampa.mpa.m, sampa.mpa.m_prime, sampa.mpa.f,
+               sampa.mpa.l, sampa.mpa.r, sampa.mpa.b, sampa.mpa.lamda_prime, sampa.mpa.beta,
+               sampa.mpa.cap_delta, sampa.mpa.pi, sampa.mpa.lamda);
+        printf("%.17g %.17g %.17g %.17g %.17g %.17g %.17g %.17g %.17g %.17g %.17g %.17g %.17g ",
+               sampa.mpa.alpha, sampa.mpa.delta, sampa.mpa.h, sampa.mpa.del_alpha,
+               sampa.mpa.delta_prime, sampa.mpa.alpha_prime, sampa.mpa.h_prime, sampa.mpa.e0,
+               sampa.mpa.del_e, sampa.mpa.e, sampa.mpa.zenith, sampa.mpa.azimuth_astro,
+               sampa.mpa.azimuth);
+        printf("%.17g %.17g %.17g %.17g %.17g %.17g %.17g %.17g %.17g %.17g %.17g ",
+               sampa.ems, sampa.rs, sampa.rm, sampa.a_sul, sampa.a_sul_pct,
+               sampa.dni, sampa.dni_sul, sampa.ghi, sampa.ghi_sul, sampa.dhi, sampa.dhi_sul);
+        printf("%.17g %.17g %.17g\n", sampa.spa.del_e, sampa.spa.e, sampa.spa.zenith);
+    }
+
+    return 0;
+}